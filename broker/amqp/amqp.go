@@ -4,7 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math/rand"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -20,23 +22,82 @@ type AMQPMessage struct {
 	event   string
 	rcvChan *amqp091.Channel
 	d       amqp091.Delivery
+	done    func()
+}
+
+// ID returns the message ID set when the message was published
+func (m *AMQPMessage) ID() string {
+	return m.d.MessageId
 }
 
 func (m *AMQPMessage) Event() string {
 	return m.event
 }
 
+// Body returns the body of the message, or nil if it could not be decoded
 func (m *AMQPMessage) Body() (data interface{}) {
-	_ = broker.Decode(m.d.Body, &data)
+	_ = m.Decode(&data)
 	return
 }
 
+// Decode decompresses the body of the message and decodes it into v
+func (m *AMQPMessage) Decode(v interface{}) error {
+	b, err := broker.Decompress(m.d.Body, m.d.ContentEncoding)
+	if err == nil {
+		err = broker.Decode(b, v)
+	}
+	if err != nil {
+		m.amqp.metrics().DecodeFailed(m.event, m.amqp.Group)
+	}
+	return err
+}
+
+// Reply sends data back to the client that called with this message. Replies go straight to the
+// queue of the caller through the default exchange.
 func (m *AMQPMessage) Reply(ctx context.Context, data interface{}) error {
-	return m.amqp.Publish(ctx, m.d.ReplyTo, data)
+	if m.d.ReplyTo == "" {
+		return broker.ErrCannotReply
+	}
+	if m.amqp.publishChan == nil {
+		return broker.ErrDisconnected
+	}
+
+	b, encoding, err := m.amqp.encode(data)
+	if err != nil {
+		return err
+	}
+
+	return m.amqp.publishChan.Publish("", m.d.ReplyTo, false, false, amqp091.Publishing{
+		Body:            b,
+		ContentEncoding: encoding,
+		CorrelationId:   m.d.CorrelationId,
+		Timestamp:       time.Now(),
+	})
 }
 
 func (m *AMQPMessage) Ack(ctx context.Context) error {
-	return m.rcvChan.Ack(m.d.DeliveryTag, false)
+	defer m.finish()
+	err := m.rcvChan.Ack(m.d.DeliveryTag, false)
+	if err == nil {
+		m.amqp.metrics().Acked(m.event, m.amqp.Group)
+	}
+	return err
+}
+
+// Nack returns the message to its queue for redelivery
+func (m *AMQPMessage) Nack(ctx context.Context) error {
+	defer m.finish()
+	err := m.rcvChan.Nack(m.d.DeliveryTag, false, true)
+	if err == nil {
+		m.amqp.metrics().Nacked(m.event, m.amqp.Group)
+	}
+	return err
+}
+
+func (m *AMQPMessage) finish() {
+	if m.done != nil {
+		m.done()
+	}
 }
 
 // AMQP is a broker for AMQP clients. Probably most useful for RabbitMQ.
@@ -44,11 +105,34 @@ type AMQP struct {
 	conn        *amqp091.Connection
 	publishChan *amqp091.Channel
 	rpcQueue    amqp091.Queue
-	rpcConsumer <-chan amqp091.Delivery
+	inFlight    broker.InFlight
+
+	// calls holds the channel awaiting the reply to each call by correlation ID
+	calls    map[string]chan amqp091.Delivery
+	callsMux sync.Mutex
+
+	// confirmChan is the channel in confirm mode that batches are published on
+	confirmChan *amqp091.Channel
+	confirms    chan amqp091.Confirmation
+	batchMux    sync.Mutex
 
 	Group    string
 	Subgroup string
 	Timeout  time.Duration
+
+	// Partitions splits every event into this many routing keys, each with its own queue. Zero
+	// disables partitioning. See PublishPartitioned.
+	Partitions int
+
+	// OwnedPartitions are the partitions this client consumes. Leave empty to consume all of them.
+	OwnedPartitions []int
+
+	// CompressThreshold is the encoded size in bytes from which message bodies are compressed. The
+	// compression is recorded in the content encoding of the message. Zero disables compression.
+	CompressThreshold int
+
+	// Metrics receives measurements from this broker, if set
+	Metrics broker.Metrics
 }
 
 // Init will initialize this broker with the given connection. Call this whenever there is a new
@@ -56,6 +140,10 @@ type AMQP struct {
 func (a *AMQP) Init(conn *amqp091.Connection) error {
 	a.conn = conn
 
+	a.batchMux.Lock()
+	a.confirmChan = nil
+	a.batchMux.Unlock()
+
 	ch, err := conn.Channel()
 	if err != nil {
 		return err
@@ -102,39 +190,106 @@ func (a *AMQP) setupRPC() error {
 	if err != nil {
 		return err
 	}
-	a.rpcConsumer = msgs
+	go a.dispatchReplies(msgs)
 
 	return nil
 }
 
+// dispatchReplies hands every reply to the call awaiting it. Calls still waiting once the
+// consumer closes get no reply.
+func (a *AMQP) dispatchReplies(replies <-chan amqp091.Delivery) {
+	for d := range replies {
+		a.callsMux.Lock()
+		if ch, ok := a.calls[d.CorrelationId]; ok {
+			ch <- d
+			delete(a.calls, d.CorrelationId)
+		}
+		a.callsMux.Unlock()
+	}
+
+	a.callsMux.Lock()
+	for correlation, ch := range a.calls {
+		close(ch)
+		delete(a.calls, correlation)
+	}
+	a.callsMux.Unlock()
+}
+
 // Publish sends data to AMQP
 func (a *AMQP) Publish(ctx context.Context, event string, data interface{}) error {
-	b, err := broker.Encode(data)
+	key := event
+	if a.Partitions > 0 {
+		key = partitionKey(event, rand.Intn(a.Partitions))
+	}
+
+	return a.publishData(event, key, data)
+}
+
+func (a *AMQP) publishData(event, key string, data interface{}) error {
+	opts, err := a.publishing(data)
 	if err != nil {
 		return err
 	}
 
-	return a.publish(event, amqp091.Publishing{
-		Body:       b,
-		Expiration: strconv.FormatInt(a.Timeout.Milliseconds(), 10),
-	})
+	return a.publish(event, key, opts)
+}
+
+// publishing encodes data into a message
+func (a *AMQP) publishing(data interface{}) (amqp091.Publishing, error) {
+	b, encoding, err := a.encode(data)
+	if err != nil {
+		return amqp091.Publishing{}, err
+	}
+
+	return amqp091.Publishing{
+		Body:            b,
+		ContentEncoding: encoding,
+		Expiration:      strconv.FormatInt(a.Timeout.Milliseconds(), 10),
+	}, nil
+}
+
+// encode encodes data for a message body, compressing it according to CompressThreshold
+func (a *AMQP) encode(data interface{}) ([]byte, string, error) {
+	b, err := broker.Encode(data)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return broker.Compress(b, a.CompressThreshold)
 }
 
-func (a *AMQP) publish(event string, opts amqp091.Publishing) error {
+func (a *AMQP) publish(event, key string, opts amqp091.Publishing) error {
 	if a.publishChan == nil {
 		return broker.ErrDisconnected
 	}
 
-	return a.publishChan.Publish(
+	if opts.Timestamp.IsZero() {
+		opts.Timestamp = time.Now()
+	}
+
+	if opts.MessageId == "" {
+		opts.MessageId = uuid.New().String()
+	}
+
+	err := a.publishChan.Publish(
 		a.Group,
-		event,
+		key,
 		false,
 		false,
 		opts,
 	)
+	if err == nil {
+		a.metrics().Published(event, a.Group)
+	}
+	return err
+}
+
+func (a *AMQP) metrics() broker.Metrics {
+	return broker.MetricsOrNop(a.Metrics)
 }
 
-// Subscribe will make this client consume for the specific event
+// Subscribe will make this client consume for the specific event. It returns nil once the broker is
+// shut down.
 func (a *AMQP) Subscribe(ctx context.Context, events []string, messages chan<- broker.Message) (err error) {
 	ch, err := a.conn.Channel()
 	if err != nil {
@@ -142,27 +297,44 @@ func (a *AMQP) Subscribe(ctx context.Context, events []string, messages chan<- b
 	}
 
 	var cancel context.CancelFunc
-	ctx, cancel = context.WithCancel(ctx)
+	ctx, cancel = a.inFlight.Context(ctx)
 	defer cancel()
 
-	errs := make(chan error)
-	defer close(errs)
-
+	var count int
+	errs := make(chan error, len(events)*len(a.routingKeys("")))
 	for _, event := range events {
-		go func(e string) {
-			errs <- a.subscribeSingle(ctx, ch, e, messages)
-		}(event)
+		for _, key := range a.routingKeys(event) {
+			count++
+			go func(e, k string) {
+				errs <- a.subscribeSingle(ctx, ch, e, k, messages)
+			}(event, key)
+		}
+	}
+
+	err = <-errs
+	cancel()
+	for i := 1; i < count; i++ {
+		<-errs
+	}
+
+	if a.inFlight.Closed() {
+		return nil
 	}
+	return
+}
 
-	return <-errs
+// Shutdown stops consuming and waits for delivered messages to be acknowledged or nacked.
+// Deliveries that were received but not yet handed out are returned to their queues.
+func (a *AMQP) Shutdown(ctx context.Context) error {
+	return a.inFlight.Shutdown(ctx)
 }
 
-func (a *AMQP) subscribeSingle(ctx context.Context, ch *amqp091.Channel, event string, messages chan<- broker.Message) (err error) {
+func (a *AMQP) subscribeSingle(ctx context.Context, ch *amqp091.Channel, event, key string, messages chan<- broker.Message) (err error) {
 	subgroup := a.Subgroup
 	if subgroup != "" {
 		subgroup += ":"
 	}
-	queueName := fmt.Sprintf("%s:%s%s", a.Group, subgroup, event)
+	queueName := fmt.Sprintf("%s:%s%s", a.Group, subgroup, key)
 
 	_, err = ch.QueueDeclare(
 		queueName,
@@ -176,12 +348,19 @@ func (a *AMQP) subscribeSingle(ctx context.Context, ch *amqp091.Channel, event s
 		return
 	}
 
-	err = ch.QueueBind(queueName, event, a.Group, false, nil)
+	err = ch.QueueBind(queueName, key, a.Group, false, nil)
 	if err != nil {
 		return
 	}
 
-	msgs, err := ch.Consume(queueName, "", false, false, false, false, nil)
+	// messages that are delayed again are routed straight back to this queue
+	err = ch.QueueBind(queueName, queueName, a.Group, false, nil)
+	if err != nil {
+		return
+	}
+
+	consumerTag := uuid.New().String()
+	msgs, err := ch.Consume(queueName, consumerTag, false, false, false, false, nil)
 	if err != nil {
 		return
 	}
@@ -189,7 +368,6 @@ func (a *AMQP) subscribeSingle(ctx context.Context, ch *amqp091.Channel, event s
 	errs := make(chan *amqp091.Error)
 	ch.NotifyClose(errs)
 
-	var consumerTag string
 	for {
 		select {
 		case <-ctx.Done():
@@ -198,6 +376,12 @@ func (a *AMQP) subscribeSingle(ctx context.Context, ch *amqp091.Channel, event s
 				return
 			}
 
+			// return deliveries that arrived before the cancellation took effect
+			for d := range msgs {
+				_ = d.Nack(false, true)
+			}
+			return
+
 		case err = <-errs:
 			return
 
@@ -206,31 +390,112 @@ func (a *AMQP) subscribeSingle(ctx context.Context, ch *amqp091.Channel, event s
 				return
 			}
 
-			consumerTag = d.ConsumerTag
-			messages <- &AMQPMessage{
-				amqp:  a,
-				event: event,
-				d:     d,
+			if a.redelay(d, queueName) {
+				continue
+			}
+
+			msg := &AMQPMessage{
+				amqp:    a,
+				event:   event,
+				rcvChan: ch,
+				d:       d,
+			}
+
+			var tracked bool
+			if msg.done, tracked = a.inFlight.Add(); !tracked {
+				_ = d.Nack(false, true)
+				continue
+			}
+
+			latency := time.Duration(-1)
+			if !d.Timestamp.IsZero() {
+				latency = time.Since(d.Timestamp)
+			}
+			a.metrics().Consumed(event, a.Group, latency)
+
+			if d.Redelivered {
+				a.metrics().Redelivered(event, a.Group)
+			}
+
+			select {
+			case messages <- msg:
+			case <-ctx.Done():
+				_ = msg.Nack(ctx)
 			}
 		}
 	}
 }
 
+// Call publishes a message and waits for its reply, returning the reply body
 func (a *AMQP) Call(event string, opts amqp091.Publishing) ([]byte, error) {
+	key := event
+	if a.Partitions > 0 {
+		key = partitionKey(event, rand.Intn(a.Partitions))
+	}
+
+	return a.call(context.Background(), event, key, opts)
+}
+
+// Request publishes data and waits for the reply to it, implementing broker.Requester
+func (a *AMQP) Request(ctx context.Context, event string, data interface{}) (interface{}, error) {
+	b, encoding, err := a.encode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	key := event
+	if a.Partitions > 0 {
+		key = partitionKey(event, rand.Intn(a.Partitions))
+	}
+
+	body, err := a.call(ctx, event, key, amqp091.Publishing{
+		Body:            b,
+		ContentEncoding: encoding,
+		Expiration:      strconv.FormatInt(a.Timeout.Milliseconds(), 10),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var reply interface{}
+	err = broker.Decode(body, &reply)
+	return reply, err
+}
+
+func (a *AMQP) call(ctx context.Context, event, key string, opts amqp091.Publishing) ([]byte, error) {
 	correlation := uuid.New().String()
 	opts.CorrelationId = correlation
 	opts.ReplyTo = a.rpcQueue.Name
 
-	err := a.publish(event, opts)
+	replies := make(chan amqp091.Delivery, 1)
+	a.callsMux.Lock()
+	if a.calls == nil {
+		a.calls = make(map[string]chan amqp091.Delivery)
+	}
+	a.calls[correlation] = replies
+	a.callsMux.Unlock()
+
+	defer func() {
+		a.callsMux.Lock()
+		delete(a.calls, correlation)
+		a.callsMux.Unlock()
+	}()
+
+	start := time.Now()
+	err := a.publish(event, key, opts)
 	if err != nil {
 		return nil, err
 	}
 
-	for d := range a.rpcConsumer {
-		if correlation == d.CorrelationId {
-			return d.Body, nil
+	select {
+	case d, ok := <-replies:
+		if !ok {
+			return nil, ErrNoRes
 		}
-	}
 
-	return nil, ErrNoRes
+		a.metrics().Called(event, a.Group, time.Since(start))
+		return broker.Decompress(d.Body, d.ContentEncoding)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
 }
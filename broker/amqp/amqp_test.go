@@ -75,3 +75,128 @@ func TestSubscribe(t *testing.T) {
 	assert.Equal(t, event, res.Event)
 	assert.EqualValues(t, data, res.Body())
 }
+
+func TestPublishAfter(t *testing.T) {
+	connect()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	msgs := make(chan broker.Message)
+	go func() {
+		err := a.Subscribe(ctx, []string{"scheduled"}, msgs)
+		assert.NoError(t, err)
+	}()
+
+	start := time.Now()
+	err := a.PublishAfter(ctx, 100*time.Millisecond, "scheduled", "bar")
+	assert.NoError(t, err)
+
+	res := <-msgs
+	assert.NoError(t, res.Ack(ctx))
+	assert.EqualValues(t, "bar", res.Body())
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+}
+
+func TestRequest(t *testing.T) {
+	connect()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	msgs := make(chan broker.Message)
+	go func() {
+		err := a.Subscribe(ctx, []string{"request"}, msgs)
+		assert.NoError(t, err)
+	}()
+
+	go func() {
+		msg := <-msgs
+		assert.NoError(t, msg.Reply(ctx, "pong"))
+		assert.NoError(t, msg.Ack(ctx))
+	}()
+
+	reply, err := a.Request(ctx, "request", "ping")
+	assert.NoError(t, err)
+	assert.EqualValues(t, "pong", reply)
+}
+
+func TestPublishBatch(t *testing.T) {
+	connect()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	msgs := make(chan broker.Message)
+	go func() {
+		err := a.Subscribe(ctx, []string{"batch"}, msgs)
+		assert.NoError(t, err)
+	}()
+
+	err := a.PublishBatch(ctx, []broker.Envelope{
+		{Event: "batch", Data: "first"},
+		{Event: "batch", Data: "second"},
+	})
+	assert.NoError(t, err)
+
+	for _, data := range []string{"first", "second"} {
+		msg := <-msgs
+		assert.NoError(t, msg.Ack(ctx))
+		assert.EqualValues(t, data, msg.Body())
+	}
+}
+
+func benchmarkBatch(size int) []broker.Envelope {
+	batch := make([]broker.Envelope, size)
+	for i := range batch {
+		batch[i] = broker.Envelope{Event: "benchmark", Data: map[string]interface{}{"content": "hello"}}
+	}
+	return batch
+}
+
+// BenchmarkPublish waits for a confirmation of every message to compare with PublishBatch, which
+// waits once per batch
+func BenchmarkPublish(b *testing.B) {
+	connect()
+
+	ctx := context.Background()
+	batch := benchmarkBatch(100)
+	for i := 0; i < b.N; i++ {
+		for _, env := range batch {
+			if err := a.PublishBatch(ctx, []broker.Envelope{env}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+	b.ReportMetric(float64(b.N*len(batch))/b.Elapsed().Seconds(), "msgs/s")
+}
+
+func BenchmarkPublishBatch(b *testing.B) {
+	connect()
+
+	ctx := context.Background()
+	batch := benchmarkBatch(100)
+	for i := 0; i < b.N; i++ {
+		if err := a.PublishBatch(ctx, batch); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(b.N*len(batch))/b.Elapsed().Seconds(), "msgs/s")
+}
+
+func TestHealth(t *testing.T) {
+	connect()
+
+	ctx := context.Background()
+	assert.NoError(t, a.Health(ctx))
+	assert.ErrorIs(t, (&AMQP{}).Health(ctx), broker.ErrDisconnected)
+}
+
+func TestDelayBucket(t *testing.T) {
+	assert.Equal(t, 100*time.Millisecond, delayBucket(time.Millisecond))
+	assert.Equal(t, 100*time.Millisecond, delayBucket(100*time.Millisecond))
+	assert.Equal(t, 10*time.Second, delayBucket(11*time.Second))
+	assert.Equal(t, time.Hour, delayBucket(61*time.Minute))
+	assert.Equal(t, 12*time.Hour, delayBucket(13*time.Hour))
+	assert.Equal(t, 24*time.Hour, delayBucket(25*time.Hour))
+}
@@ -0,0 +1,101 @@
+package amqp
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rabbitmq/amqp091-go"
+	"github.com/spec-tacles/go/broker"
+)
+
+// ErrNacked occurs when the server could not take responsibility for a published message
+var ErrNacked = errors.New("message was nacked by the server")
+
+// PublishBatch publishes every message on a channel in confirm mode, then waits until the server
+// confirmed all of them. Batches are published one at a time.
+func (a *AMQP) PublishBatch(ctx context.Context, batch []broker.Envelope) error {
+	if a.conn == nil {
+		return broker.ErrDisconnected
+	}
+
+	a.batchMux.Lock()
+	defer a.batchMux.Unlock()
+
+	if a.confirmChan == nil {
+		ch, err := a.conn.Channel()
+		if err != nil {
+			return err
+		}
+
+		if err = ch.Confirm(false); err != nil {
+			_ = ch.Close()
+			return err
+		}
+
+		a.confirms = ch.NotifyPublish(make(chan amqp091.Confirmation, 1))
+		a.confirmChan = ch
+	}
+
+	// read confirmations while publishing so that the connection never blocks on delivering them
+	confirmed := make(chan error, 1)
+	go func(confirms <-chan amqp091.Confirmation, n int) {
+		var err error
+		for i := 0; i < n; i++ {
+			c, ok := <-confirms
+			if !ok {
+				err = broker.ErrDisconnected
+				break
+			}
+			if !c.Ack && err == nil {
+				err = ErrNacked
+			}
+		}
+		confirmed <- err
+	}(a.confirms, len(batch))
+
+	// closing the channel ends the confirmations that will never arrive
+	abort := func(err error) error {
+		_ = a.confirmChan.Close()
+		a.confirmChan = nil
+		<-confirmed
+		return err
+	}
+
+	for _, env := range batch {
+		opts, err := a.publishing(env.Data)
+		if err != nil {
+			return abort(err)
+		}
+
+		key := env.Event
+		if a.Partitions > 0 {
+			key = partitionKey(env.Event, rand.Intn(a.Partitions))
+		}
+
+		opts.Timestamp = time.Now()
+		opts.MessageId = uuid.New().String()
+		if err = a.confirmChan.Publish(a.Group, key, false, false, opts); err != nil {
+			return abort(err)
+		}
+	}
+
+	select {
+	case err := <-confirmed:
+		if err == broker.ErrDisconnected {
+			a.confirmChan = nil
+		}
+		if err != nil {
+			return err
+		}
+	case <-ctx.Done():
+		return abort(ctx.Err())
+	}
+
+	for _, env := range batch {
+		a.metrics().Published(env.Event, a.Group)
+	}
+	return nil
+}
@@ -0,0 +1,15 @@
+package amqp
+
+import (
+	"context"
+
+	"github.com/spec-tacles/go/broker"
+)
+
+// Health checks that the connection and the publishing channel are open
+func (a *AMQP) Health(ctx context.Context) error {
+	if a.conn == nil || a.conn.IsClosed() || a.publishChan == nil || a.publishChan.IsClosed() {
+		return broker.ErrDisconnected
+	}
+	return nil
+}
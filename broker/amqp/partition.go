@@ -0,0 +1,38 @@
+package amqp
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/spec-tacles/go/broker"
+)
+
+// PublishPartitioned publishes a message to the partition of the event that key belongs to, such as
+// the ID of the guild the event is for. Each partition has its own queue, so as long as a single
+// client consumes it, messages with the same key are handled in the order they were published.
+func (a *AMQP) PublishPartitioned(ctx context.Context, event, key string, data interface{}) error {
+	routingKey := event
+	if a.Partitions > 0 {
+		routingKey = partitionKey(event, broker.Partition(key, a.Partitions))
+	}
+
+	return a.publishData(event, routingKey, data)
+}
+
+func partitionKey(event string, partition int) string {
+	return event + ":" + strconv.Itoa(partition)
+}
+
+// routingKeys returns the routing keys this client consumes for an event
+func (a *AMQP) routingKeys(event string) []string {
+	if a.Partitions <= 0 {
+		return []string{event}
+	}
+
+	owned := broker.OwnedPartitions(a.Partitions, a.OwnedPartitions)
+	keys := make([]string, len(owned))
+	for i, partition := range owned {
+		keys[i] = partitionKey(event, partition)
+	}
+	return keys
+}
@@ -0,0 +1,169 @@
+package amqp
+
+import (
+	"context"
+	"math/rand"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rabbitmq/amqp091-go"
+	"github.com/spec-tacles/go/broker"
+)
+
+const (
+	// delayHeader is matched by the bindings of delay queues
+	delayHeader = "x-spectacles-delay"
+
+	// dueHeader holds the time in Unix milliseconds at which a delayed message is due
+	dueHeader = "x-spectacles-due"
+)
+
+// delayBuckets are the delays that have their own queue, from shortest to longest
+var delayBuckets = []time.Duration{
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2 * time.Second,
+	5 * time.Second,
+	10 * time.Second,
+	15 * time.Second,
+	30 * time.Second,
+	time.Minute,
+	2 * time.Minute,
+	5 * time.Minute,
+	10 * time.Minute,
+	15 * time.Minute,
+	30 * time.Minute,
+	time.Hour,
+	2 * time.Hour,
+	6 * time.Hour,
+	12 * time.Hour,
+	24 * time.Hour,
+}
+
+// delayBucket returns the longest bucket that does not exceed the remaining delay, or the shortest
+// bucket if they all do
+func delayBucket(d time.Duration) time.Duration {
+	bucket := delayBuckets[0]
+	for _, b := range delayBuckets[1:] {
+		if b > d {
+			break
+		}
+		bucket = b
+	}
+	return bucket
+}
+
+// PublishAt publishes a message once the given time has passed. The message waits in the delay
+// queue of the longest bucket that fits the remaining delay until its TTL expires, at which point
+// it is dead-lettered into the group exchange with its original routing key. Subscribers delay
+// messages that arrive before they are due again, so a message goes through as many buckets as it
+// needs and arrives at most one shortest bucket late. Delay queues delete themselves a minute after
+// they were last used.
+func (a *AMQP) PublishAt(ctx context.Context, t time.Time, event string, data interface{}) error {
+	if !time.Now().Before(t) {
+		return a.Publish(ctx, event, data)
+	}
+
+	b, encoding, err := a.encode(data)
+	if err != nil {
+		return err
+	}
+
+	key := event
+	if a.Partitions > 0 {
+		key = partitionKey(event, rand.Intn(a.Partitions))
+	}
+
+	return a.delay(key, t, amqp091.Publishing{
+		Body:            b,
+		ContentEncoding: encoding,
+		MessageId:       uuid.New().String(),
+	})
+}
+
+// PublishAfter publishes a message once the given duration has passed
+func (a *AMQP) PublishAfter(ctx context.Context, d time.Duration, event string, data interface{}) error {
+	return a.PublishAt(ctx, time.Now().Add(d), event, data)
+}
+
+// delay publishes a message to the delay queue that fits the time left until it is due
+func (a *AMQP) delay(key string, due time.Time, opts amqp091.Publishing) error {
+	if a.publishChan == nil {
+		return broker.ErrDisconnected
+	}
+
+	d := delayBucket(time.Until(due))
+	exchange, err := a.declareDelay(d)
+	if err != nil {
+		return err
+	}
+
+	delay := strconv.FormatInt(d.Milliseconds(), 10)
+	opts.Headers = amqp091.Table{delayHeader: delay, dueHeader: due.UnixMilli()}
+	opts.Expiration = delay
+	opts.Timestamp = due
+	return a.publishChan.Publish(exchange, key, false, false, opts)
+}
+
+// redelay delays a delivery again if it was dead-lettered before it is due, reporting whether it
+// did. It is routed back to the queue it was received from only, so that every subgroup delays its
+// own copy. Deliveries that cannot be delayed again are requeued.
+func (a *AMQP) redelay(d amqp091.Delivery, queue string) bool {
+	var due time.Time
+	switch ms := d.Headers[dueHeader].(type) {
+	case int64:
+		due = time.UnixMilli(ms)
+	case int32:
+		due = time.UnixMilli(int64(ms))
+	default:
+		return false
+	}
+
+	if !time.Now().Before(due) {
+		return false
+	}
+
+	err := a.delay(queue, due, amqp091.Publishing{
+		Body:            d.Body,
+		ContentEncoding: d.ContentEncoding,
+		MessageId:       d.MessageId,
+	})
+	if err != nil {
+		_ = d.Nack(false, true)
+	} else {
+		_ = d.Ack(false)
+	}
+	return true
+}
+
+// declareDelay declares the queue for messages with the given delay, returning the exchange to
+// publish them to. Declaring it for every message keeps it from expiring while it is still used.
+func (a *AMQP) declareDelay(d time.Duration) (exchange string, err error) {
+	exchange = a.Group + ":delayed"
+	err = a.publishChan.ExchangeDeclare(exchange, "headers", true, false, false, false, nil)
+	if err != nil {
+		return
+	}
+
+	ttl := d.Milliseconds()
+	delay := strconv.FormatInt(ttl, 10)
+	queueName := exchange + ":" + delay
+
+	_, err = a.publishChan.QueueDeclare(queueName, true, false, false, false, amqp091.Table{
+		"x-dead-letter-exchange": a.Group,
+		"x-message-ttl":          ttl,
+		"x-expires":              ttl + time.Minute.Milliseconds(),
+	})
+	if err != nil {
+		return
+	}
+
+	err = a.publishChan.QueueBind(queueName, "", exchange, false, amqp091.Table{
+		"x-match":   "all",
+		delayHeader: delay,
+	})
+	return
+}
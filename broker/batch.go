@@ -0,0 +1,30 @@
+package broker
+
+import "context"
+
+// Envelope is a message to publish as part of a batch
+type Envelope struct {
+	Event string
+	Data  interface{}
+}
+
+// BatchPublisher is implemented by brokers that can publish several messages at once, which saves
+// round trips over publishing them one by one
+type BatchPublisher interface {
+	PublishBatch(ctx context.Context, batch []Envelope) error
+}
+
+// PublishBatch publishes a batch of messages with the broker, at once if it is a BatchPublisher
+// and one by one otherwise
+func PublishBatch(ctx context.Context, b Broker, batch []Envelope) error {
+	if p, ok := b.(BatchPublisher); ok {
+		return p.PublishBatch(ctx, batch)
+	}
+
+	for _, env := range batch {
+		if err := b.Publish(ctx, env.Event, env.Data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package broker
+
+import (
+	"context"
+	"sync"
+)
+
+// Requester is implemented by brokers that can publish a message and wait for the reply to it
+type Requester interface {
+	Request(ctx context.Context, event string, data interface{}) (interface{}, error)
+}
+
+// Bridge forwards events from one broker to another, such as while migrating between transports.
+// A message is acknowledged on the source only once it was published to the target, and is nacked
+// if that fails so that it is redelivered. Messages whose body cannot be decoded are nacked rather
+// than forwarded without it.
+type Bridge struct {
+	From Broker
+	To   Broker
+
+	// Events are the events to forward
+	Events []string
+
+	// Rename maps events to the name they are published as on the target. Events that are not in
+	// it keep their name.
+	Rename map[string]string
+
+	// Filter decides whether to forward a message, if set. Messages it rejects are acknowledged
+	// and dropped.
+	Filter func(msg Message) bool
+
+	// Requests are the events whose messages are RPC requests. These are forwarded with Request,
+	// which To must implement, and the reply is sent back to the source message. Requests are
+	// forwarded concurrently, so they may arrive out of order.
+	Requests map[string]bool
+
+	// OnError is called with every message that could not be forwarded, if set
+	OnError func(msg Message, err error)
+}
+
+// Run forwards messages until the context is cancelled or the source subscription ends, then waits
+// for pending requests. It returns the error that ended the subscription.
+func (b *Bridge) Run(ctx context.Context) error {
+	var requests sync.WaitGroup
+	err := relay(ctx, b.From, b.Events, nil, func(msg Message) Message {
+		if b.Filter != nil && !b.Filter(msg) {
+			_ = msg.Ack(ctx)
+			return nil
+		}
+
+		if b.Requests[msg.Event()] {
+			requests.Add(1)
+			go func() {
+				defer requests.Done()
+				b.forward(ctx, msg, b.request)
+			}()
+			return nil
+		}
+
+		b.forward(ctx, msg, b.publish)
+		return nil
+	})
+
+	requests.Wait()
+	return err
+}
+
+func (b *Bridge) forward(ctx context.Context, msg Message, send func(context.Context, Message) error) {
+	if err := send(ctx, msg); err != nil {
+		_ = Nack(ctx, msg)
+		if b.OnError != nil {
+			b.OnError(msg, err)
+		}
+		return
+	}
+
+	_ = msg.Ack(ctx)
+}
+
+func (b *Bridge) publish(ctx context.Context, msg Message) error {
+	body, err := DecodeBody(msg)
+	if err != nil {
+		return err
+	}
+
+	return b.To.Publish(ctx, b.rename(msg.Event()), body)
+}
+
+func (b *Bridge) request(ctx context.Context, msg Message) error {
+	requester, ok := b.To.(Requester)
+	if !ok {
+		return ErrCannotReply
+	}
+
+	body, err := DecodeBody(msg)
+	if err != nil {
+		return err
+	}
+
+	reply, err := requester.Request(ctx, b.rename(msg.Event()), body)
+	if err != nil {
+		return err
+	}
+
+	return msg.Reply(ctx, reply)
+}
+
+func (b *Bridge) rename(event string) string {
+	if name, ok := b.Rename[event]; ok {
+		return name
+	}
+	return event
+}
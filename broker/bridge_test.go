@@ -0,0 +1,143 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type bridgeMessage struct {
+	testMessage
+	nacked bool
+	reply  interface{}
+}
+
+func (m *bridgeMessage) Nack(context.Context) error {
+	m.nacked = true
+	return nil
+}
+
+func (m *bridgeMessage) Reply(ctx context.Context, data interface{}) error {
+	m.reply = data
+	return nil
+}
+
+type bridgeSource []*bridgeMessage
+
+func (b bridgeSource) Publish(ctx context.Context, event string, data interface{}) error {
+	return nil
+}
+
+func (b bridgeSource) Subscribe(ctx context.Context, events []string, messages chan<- Message) error {
+	for _, msg := range b {
+		messages <- msg
+	}
+	return nil
+}
+
+type bridgeTarget struct {
+	mux       sync.Mutex
+	published []string
+}
+
+var errUnavailable = errors.New("unavailable")
+
+func (b *bridgeTarget) Publish(ctx context.Context, event string, data interface{}) error {
+	if data == "fail" {
+		return errUnavailable
+	}
+
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	b.published = append(b.published, event)
+	return nil
+}
+
+func (b *bridgeTarget) Subscribe(ctx context.Context, events []string, messages chan<- Message) error {
+	return nil
+}
+
+func (b *bridgeTarget) Request(ctx context.Context, event string, data interface{}) (interface{}, error) {
+	return event + " reply", nil
+}
+
+// undecodableMessage is a message whose body cannot be decoded
+type undecodableMessage struct {
+	bridgeMessage
+}
+
+var errUndecodable = errors.New("undecodable")
+
+func (m *undecodableMessage) Decode(v interface{}) error {
+	return errUndecodable
+}
+
+type undecodableSource []*undecodableMessage
+
+func (b undecodableSource) Publish(ctx context.Context, event string, data interface{}) error {
+	return nil
+}
+
+func (b undecodableSource) Subscribe(ctx context.Context, events []string, messages chan<- Message) error {
+	for _, msg := range b {
+		messages <- msg
+	}
+	return nil
+}
+
+func TestBridge(t *testing.T) {
+	message := func(event string, data interface{}) *bridgeMessage {
+		return &bridgeMessage{testMessage: testMessage{IOPacket: IOPacket{E: event, D: data}}}
+	}
+
+	forwarded := message("MESSAGE_CREATE", "foo")
+	renamed := message("GUILD_CREATE", "bar")
+	filtered := message("TYPING_START", "baz")
+	failed := message("MESSAGE_CREATE", "fail")
+	request := message("COMMAND", "ping")
+
+	target := &bridgeTarget{}
+	var errs []error
+	bridge := &Bridge{
+		From:     bridgeSource{forwarded, renamed, filtered, failed, request},
+		To:       target,
+		Rename:   map[string]string{"GUILD_CREATE": "guilds", "COMMAND": "commands"},
+		Filter:   func(msg Message) bool { return msg.Event() != "TYPING_START" },
+		Requests: map[string]bool{"COMMAND": true},
+		OnError:  func(msg Message, err error) { errs = append(errs, err) },
+	}
+
+	assert.NoError(t, bridge.Run(context.Background()))
+	assert.Equal(t, []string{"MESSAGE_CREATE", "guilds"}, target.published)
+
+	assert.True(t, forwarded.acked)
+	assert.True(t, renamed.acked)
+	assert.True(t, filtered.acked)
+
+	assert.False(t, failed.acked)
+	assert.True(t, failed.nacked)
+	assert.Equal(t, []error{errUnavailable}, errs)
+
+	assert.True(t, request.acked)
+	assert.Equal(t, "commands reply", request.reply)
+}
+
+func TestBridgeUndecodable(t *testing.T) {
+	msg := &undecodableMessage{bridgeMessage{testMessage: testMessage{IOPacket: IOPacket{E: "MESSAGE_CREATE"}}}}
+	target := &bridgeTarget{}
+	var errs []error
+	bridge := &Bridge{
+		From:    undecodableSource{msg},
+		To:      target,
+		OnError: func(msg Message, err error) { errs = append(errs, err) },
+	}
+
+	assert.NoError(t, bridge.Run(context.Background()))
+	assert.Empty(t, target.published)
+	assert.False(t, msg.acked)
+	assert.True(t, msg.nacked)
+	assert.Equal(t, []error{errUndecodable}, errs)
+}
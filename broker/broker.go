@@ -16,6 +16,50 @@ type Message interface {
 	Ack(ctx context.Context) error
 }
 
+// Identifier is implemented by messages that have an ID
+type Identifier interface {
+	// ID uniquely identifies the message within its event
+	ID() string
+}
+
+// Nacker is implemented by messages that can be given up on
+type Nacker interface {
+	// Nack gives up on the message, returning it to the broker for redelivery where possible
+	Nack(ctx context.Context) error
+}
+
+// Decoder is implemented by messages that can report why their body could not be decoded
+type Decoder interface {
+	// Decode decodes the body of the message into v
+	Decode(v interface{}) error
+}
+
+// DecodeBody returns the body of a message, or an error if it is a Decoder and its body could not
+// be decoded
+func DecodeBody(msg Message) (body interface{}, err error) {
+	if d, ok := msg.(Decoder); ok {
+		err = d.Decode(&body)
+		return
+	}
+	return msg.Body(), nil
+}
+
+// MessageID returns the ID of a message, or an empty string if it has none
+func MessageID(msg Message) string {
+	if i, ok := msg.(Identifier); ok {
+		return i.ID()
+	}
+	return ""
+}
+
+// Nack gives up on a message if it is a Nacker and does nothing otherwise
+func Nack(ctx context.Context, msg Message) error {
+	if n, ok := msg.(Nacker); ok {
+		return n.Nack(ctx)
+	}
+	return nil
+}
+
 // Broker is an interface describing message brokers
 type Broker interface {
 	Publish(ctx context.Context, event string, data interface{}) error
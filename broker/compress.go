@@ -0,0 +1,65 @@
+package broker
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io/ioutil"
+	"sync"
+)
+
+// EncodingGzip is the content encoding of message bodies compressed with gzip
+const EncodingGzip = "gzip"
+
+// ErrUnknownEncoding occurs when a message body has a content encoding that cannot be decompressed
+var ErrUnknownEncoding = errors.New("unknown content encoding")
+
+var gzipWriters = sync.Pool{
+	New: func() interface{} {
+		return gzip.NewWriter(nil)
+	},
+}
+
+// Compress gzips encoded data that is at least threshold bytes long. It returns the data to send
+// along with its content encoding, which is empty if the data was left as it is. Data is also left
+// as it is when compressing would not make it smaller. A threshold of zero disables compression.
+func Compress(data []byte, threshold int) ([]byte, string, error) {
+	if threshold <= 0 || len(data) < threshold {
+		return data, "", nil
+	}
+
+	buf := bytes.NewBuffer(make([]byte, 0, len(data)/4))
+	w := gzipWriters.Get().(*gzip.Writer)
+	defer gzipWriters.Put(w)
+
+	w.Reset(buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, "", err
+	}
+	if err := w.Close(); err != nil {
+		return nil, "", err
+	}
+
+	if buf.Len() >= len(data) {
+		return data, "", nil
+	}
+	return buf.Bytes(), EncodingGzip, nil
+}
+
+// Decompress reverses Compress given the content encoding it returned
+func Decompress(data []byte, encoding string) ([]byte, error) {
+	switch encoding {
+	case "":
+		return data, nil
+	case EncodingGzip:
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+
+		return ioutil.ReadAll(r)
+	default:
+		return nil, ErrUnknownEncoding
+	}
+}
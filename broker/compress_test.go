@@ -0,0 +1,116 @@
+package broker
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// guildCreate resembles the payload of a GUILD_CREATE for a guild with the given number of members
+func guildCreate(members int) map[string]interface{} {
+	list := make([]interface{}, members)
+	for i := range list {
+		id := strconv.Itoa(100000000000000000 + i*7919)
+		list[i] = map[string]interface{}{
+			"user": map[string]interface{}{
+				"id":            id,
+				"username":      "member" + strconv.Itoa(i),
+				"discriminator": strconv.Itoa(1000 + i%9000),
+				"avatar":        nil,
+			},
+			"roles":     []interface{}{"41771983423143936", "41771983423143937"},
+			"joined_at": "2021-06-01T12:00:00.000000+00:00",
+			"deaf":      false,
+			"mute":      false,
+		}
+	}
+
+	return map[string]interface{}{
+		"id":           "41771983423143937",
+		"name":         "A large guild",
+		"member_count": members,
+		"members":      list,
+	}
+}
+
+func TestCompress(t *testing.T) {
+	data, err := Encode(guildCreate(100))
+	assert.NoError(t, err)
+
+	compressed, encoding, err := Compress(data, 1024)
+	assert.NoError(t, err)
+	assert.Equal(t, EncodingGzip, encoding)
+	assert.Less(t, len(compressed), len(data))
+
+	decompressed, err := Decompress(compressed, encoding)
+	assert.NoError(t, err)
+	assert.Equal(t, data, decompressed)
+
+	small, encoding, err := Compress(data, len(data)+1)
+	assert.NoError(t, err)
+	assert.Empty(t, encoding)
+	assert.Equal(t, data, small)
+
+	_, encoding, err = Compress(data, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, encoding)
+
+	_, err = Decompress(data, "br")
+	assert.ErrorIs(t, err, ErrUnknownEncoding)
+}
+
+func BenchmarkEncode(b *testing.B) {
+	payload := guildCreate(10000)
+	b.ReportAllocs()
+
+	var size int
+	for i := 0; i < b.N; i++ {
+		data, err := Encode(payload)
+		if err != nil {
+			b.Fatal(err)
+		}
+		size = len(data)
+	}
+	b.ReportMetric(float64(size), "bytes/msg")
+}
+
+func BenchmarkEncodeCompressed(b *testing.B) {
+	payload := guildCreate(10000)
+	b.ReportAllocs()
+
+	var size int
+	for i := 0; i < b.N; i++ {
+		data, err := Encode(payload)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		data, _, err = Compress(data, 1024)
+		if err != nil {
+			b.Fatal(err)
+		}
+		size = len(data)
+	}
+	b.ReportMetric(float64(size), "bytes/msg")
+}
+
+func BenchmarkDecompress(b *testing.B) {
+	data, err := Encode(guildCreate(10000))
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	compressed, encoding, err := Compress(data, 1024)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := Decompress(compressed, encoding); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
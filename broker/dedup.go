@@ -0,0 +1,201 @@
+package broker
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// DefaultDedupLease is how long Dedup claims a message for while it is processed, unless its Lease
+// is set
+const DefaultDedupLease = 30 * time.Second
+
+// ErrDedupWindow occurs when the window or lease of a Dedup is shorter than a millisecond
+var ErrDedupWindow = errors.New("dedup windows and leases must be at least a millisecond")
+
+// DedupStore remembers which messages are being processed and which have been processed
+type DedupStore interface {
+	// Claim marks a key as being processed for the duration of the lease, unless it is already
+	// marked. It reports whether the key was claimed and, if it was not, whether it is processed.
+	Claim(ctx context.Context, key string, lease time.Duration) (claimed, committed bool, err error)
+
+	// Commit marks a key as processed for the duration of the window
+	Commit(ctx context.Context, key string, window time.Duration) error
+
+	// Release unmarks a key so that its message can be processed again
+	Release(ctx context.Context, key string) error
+}
+
+// Dedup wraps a broker to skip messages that were already processed within Window, which happens
+// when a message is redelivered after its consumer acknowledged it too late. Messages are keyed on
+// their event and ID; messages without an ID are never skipped.
+//
+// A message is claimed for Lease when it is received, committed for Window once it is
+// acknowledged, and released when it is nacked. Redeliveries of committed messages are
+// acknowledged and skipped. Redeliveries of messages that are still claimed by another consumer
+// are nacked, so that they come back once that consumer is done or its lease runs out.
+type Dedup struct {
+	Broker
+	Store  DedupStore
+	Window time.Duration
+	Lease  time.Duration
+}
+
+type dedupMessage struct {
+	Message
+	d   *Dedup
+	key string
+}
+
+// Ack commits the key of the message, then acknowledges it. The message is acknowledged even if
+// committing fails, since it has been processed either way, and the error is returned.
+func (m *dedupMessage) Ack(ctx context.Context) error {
+	err := m.d.Store.Commit(ctx, m.key, m.d.Window)
+	if ackErr := m.Message.Ack(ctx); ackErr != nil {
+		return ackErr
+	}
+	return err
+}
+
+// Nack releases the key of the message, then nacks it
+func (m *dedupMessage) Nack(ctx context.Context) error {
+	err := m.d.Store.Release(ctx, m.key)
+	if nackErr := Nack(ctx, m.Message); nackErr != nil {
+		return nackErr
+	}
+	return err
+}
+
+func (m *dedupMessage) ID() string {
+	return MessageID(m.Message)
+}
+
+// Decode decodes the body of the wrapped message into v, reporting why it failed if the wrapped
+// message is a Decoder
+func (m *dedupMessage) Decode(v interface{}) error {
+	if d, ok := m.Message.(Decoder); ok {
+		return d.Decode(v)
+	}
+
+	b, err := Encode(m.Message.Body())
+	if err != nil {
+		return err
+	}
+	return Decode(b, v)
+}
+
+func (d *Dedup) lease() time.Duration {
+	if d.Lease == 0 {
+		return DefaultDedupLease
+	}
+	return d.Lease
+}
+
+// Subscribe implements Broker interface
+func (d *Dedup) Subscribe(ctx context.Context, events []string, messages chan<- Message) error {
+	if d.Window < time.Millisecond || d.lease() < time.Millisecond {
+		return ErrDedupWindow
+	}
+
+	return relay(ctx, d.Broker, events, messages, func(msg Message) Message {
+		id := MessageID(msg)
+		if id == "" {
+			return msg
+		}
+
+		key := msg.Event() + ":" + id
+		claimed, committed, err := d.Store.Claim(ctx, key, d.lease())
+
+		// if the store is unavailable, process the message anyway rather than risk losing it
+		switch {
+		case err != nil || claimed:
+			return &dedupMessage{Message: msg, d: d, key: key}
+		case committed:
+			_ = msg.Ack(ctx)
+		default:
+			_ = Nack(ctx, msg)
+		}
+		return nil
+	})
+}
+
+// MemoryDedupStore is a DedupStore that keeps up to Size keys in memory, forgetting the least
+// recently marked keys first. It is only shared by consumers in the same process.
+type MemoryDedupStore struct {
+	mux     sync.Mutex
+	size    int
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type dedupEntry struct {
+	key       string
+	committed bool
+	expires   time.Time
+}
+
+// NewMemoryDedupStore makes a new in-memory store holding up to size keys
+func NewMemoryDedupStore(size int) *MemoryDedupStore {
+	return &MemoryDedupStore{
+		size:    size,
+		order:   list.New(),
+		entries: make(map[string]*list.Element, size),
+	}
+}
+
+// Claim implements DedupStore
+func (s *MemoryDedupStore) Claim(ctx context.Context, key string, lease time.Duration) (bool, bool, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if el, ok := s.entries[key]; ok {
+		entry := el.Value.(*dedupEntry)
+		if time.Now().Before(entry.expires) {
+			return false, entry.committed, nil
+		}
+	}
+
+	s.set(key, false, lease)
+	return true, false, nil
+}
+
+// Commit implements DedupStore
+func (s *MemoryDedupStore) Commit(ctx context.Context, key string, window time.Duration) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.set(key, true, window)
+	return nil
+}
+
+// Release implements DedupStore
+func (s *MemoryDedupStore) Release(ctx context.Context, key string) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if el, ok := s.entries[key]; ok {
+		s.order.Remove(el)
+		delete(s.entries, key)
+	}
+	return nil
+}
+
+// set marks a key for the given duration, evicting the least recently marked keys once the store
+// is full
+func (s *MemoryDedupStore) set(key string, committed bool, d time.Duration) {
+	entry := &dedupEntry{key, committed, time.Now().Add(d)}
+	if el, ok := s.entries[key]; ok {
+		el.Value = entry
+		s.order.MoveToFront(el)
+		return
+	}
+
+	s.entries[key] = s.order.PushFront(entry)
+	for s.order.Len() > s.size {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.entries, oldest.Value.(*dedupEntry).key)
+	}
+}
@@ -0,0 +1,145 @@
+package broker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testMessage struct {
+	IOPacket
+	id    string
+	acked bool
+}
+
+func (m *testMessage) ID() string {
+	return m.id
+}
+
+func (m *testMessage) Ack(context.Context) error {
+	m.acked = true
+	return nil
+}
+
+type testBroker []*testMessage
+
+func (b testBroker) Publish(ctx context.Context, event string, data interface{}) error {
+	return nil
+}
+
+func (b testBroker) Subscribe(ctx context.Context, events []string, messages chan<- Message) error {
+	for _, msg := range b {
+		messages <- msg
+	}
+	return nil
+}
+
+func TestDedup(t *testing.T) {
+	ctx := context.Background()
+	first := &testMessage{IOPacket: IOPacket{E: "foo"}, id: "1"}
+	duplicate := &testMessage{IOPacket: IOPacket{E: "foo"}, id: "1"}
+	other := &testMessage{IOPacket: IOPacket{E: "bar"}, id: "1"}
+
+	d := &Dedup{
+		Broker: testBroker{first},
+		Store:  NewMemoryDedupStore(10),
+		Window: time.Minute,
+	}
+
+	msgs := make(chan Message, 2)
+	assert.NoError(t, d.Subscribe(ctx, []string{"foo", "bar"}, msgs))
+	assert.NoError(t, (<-msgs).Ack(ctx))
+	assert.True(t, first.acked)
+
+	d.Broker = testBroker{duplicate, other}
+	assert.NoError(t, d.Subscribe(ctx, []string{"foo", "bar"}, msgs))
+	assert.Len(t, msgs, 1)
+	assert.Equal(t, "bar", (<-msgs).Event())
+	assert.True(t, duplicate.acked)
+}
+
+func TestDedupInProgress(t *testing.T) {
+	ctx := context.Background()
+	first := &bridgeMessage{testMessage: testMessage{IOPacket: IOPacket{E: "foo"}, id: "1"}}
+	concurrent := &bridgeMessage{testMessage: testMessage{IOPacket: IOPacket{E: "foo"}, id: "1"}}
+	redelivered := &bridgeMessage{testMessage: testMessage{IOPacket: IOPacket{E: "foo"}, id: "1"}}
+
+	d := &Dedup{
+		Broker: bridgeSource{first, concurrent},
+		Store:  NewMemoryDedupStore(10),
+		Window: time.Minute,
+	}
+
+	// the first delivery is still being processed, so the concurrent one is returned to the broker
+	msgs := make(chan Message, 2)
+	assert.NoError(t, d.Subscribe(ctx, []string{"foo"}, msgs))
+	assert.Len(t, msgs, 1)
+	assert.True(t, concurrent.nacked)
+	assert.False(t, concurrent.acked)
+
+	// once the first delivery is nacked, its redelivery is processed
+	assert.NoError(t, Nack(ctx, <-msgs))
+	assert.True(t, first.nacked)
+
+	d.Broker = bridgeSource{redelivered}
+	assert.NoError(t, d.Subscribe(ctx, []string{"foo"}, msgs))
+	assert.Len(t, msgs, 1)
+}
+
+func TestDedupWindow(t *testing.T) {
+	d := &Dedup{Broker: testBroker{}, Store: NewMemoryDedupStore(10)}
+	assert.Equal(t, ErrDedupWindow, d.Subscribe(context.Background(), []string{"foo"}, make(chan Message)))
+}
+
+func TestMemoryDedupStore(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryDedupStore(2)
+
+	claimed, _, _ := s.Claim(ctx, "a", time.Minute)
+	assert.True(t, claimed)
+	claimed, committed, _ := s.Claim(ctx, "a", time.Minute)
+	assert.False(t, claimed)
+	assert.False(t, committed)
+
+	assert.NoError(t, s.Commit(ctx, "a", time.Minute))
+	claimed, committed, _ = s.Claim(ctx, "a", time.Minute)
+	assert.False(t, claimed)
+	assert.True(t, committed)
+
+	assert.NoError(t, s.Release(ctx, "a"))
+	claimed, _, _ = s.Claim(ctx, "a", time.Minute)
+	assert.True(t, claimed)
+
+	// "a" is evicted once the store is full
+	_ = s.Commit(ctx, "b", time.Minute)
+	_ = s.Commit(ctx, "c", time.Minute)
+	claimed, _, _ = s.Claim(ctx, "a", time.Minute)
+	assert.True(t, claimed)
+
+	// expired leases can be claimed again
+	claimed, _, _ = s.Claim(ctx, "d", 0)
+	assert.True(t, claimed)
+	claimed, _, _ = s.Claim(ctx, "d", 0)
+	assert.True(t, claimed)
+}
+
+func TestDedupDecode(t *testing.T) {
+	ctx := context.Background()
+	plain := &bridgeMessage{testMessage: testMessage{IOPacket: IOPacket{E: "foo", D: "bar"}, id: "1"}}
+	broken := &undecodableMessage{bridgeMessage{testMessage: testMessage{IOPacket: IOPacket{E: "foo"}, id: "2"}}}
+
+	msgs := make(chan Message, 2)
+	d := &Dedup{Broker: bridgeSource{plain}, Store: NewMemoryDedupStore(10), Window: time.Minute}
+	assert.NoError(t, d.Subscribe(ctx, []string{"foo"}, msgs))
+	d.Broker = undecodableSource{broken}
+	assert.NoError(t, d.Subscribe(ctx, []string{"foo"}, msgs))
+
+	body, err := DecodeBody(<-msgs)
+	assert.NoError(t, err)
+	assert.EqualValues(t, "bar", body)
+
+	_, err = DecodeBody(<-msgs)
+	assert.Equal(t, errUndecodable, err)
+}
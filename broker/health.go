@@ -0,0 +1,130 @@
+package broker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// HealthChecker is implemented by brokers that can check their connection
+type HealthChecker interface {
+	Health(ctx context.Context) error
+}
+
+var (
+	errIdle          = errors.New("no message received within the idle limit")
+	errNotSubscribed = errors.New("not subscribed")
+)
+
+// Monitor wraps a broker to track the state of its subscriptions, and serves it over HTTP for
+// readiness probes. The response status is 503 while the broker is unhealthy, nothing is
+// subscribed, or no message arrived within MaxIdle.
+type Monitor struct {
+	Broker
+
+	// MaxIdle is how long the broker may go without receiving a message before it is considered
+	// unhealthy. Zero disables the check.
+	MaxIdle time.Duration
+
+	mux           sync.Mutex
+	subscriptions int
+	lastMessage   time.Time
+	failed        bool
+	reconnects    int
+}
+
+// Status describes the state of a monitored broker
+type Status struct {
+	Healthy bool   `json:"healthy"`
+	Error   string `json:"error,omitempty"`
+
+	// Subscriptions is the number of subscriptions currently running
+	Subscriptions int `json:"subscriptions"`
+
+	// LastMessageAge is the number of seconds since the last message was received, or nil if none
+	// was received yet
+	LastMessageAge *float64 `json:"last_message_age"`
+
+	// Reconnects is the number of subscriptions started after a previous one failed
+	Reconnects int `json:"reconnects"`
+}
+
+// Health checks the connection of the broker, if it is a HealthChecker
+func (m *Monitor) Health(ctx context.Context) error {
+	if h, ok := m.Broker.(HealthChecker); ok {
+		return h.Health(ctx)
+	}
+	return nil
+}
+
+// Subscribe implements Broker interface, tracking the subscription and its messages
+func (m *Monitor) Subscribe(ctx context.Context, events []string, messages chan<- Message) error {
+	m.mux.Lock()
+	m.subscriptions++
+	if m.failed {
+		m.reconnects++
+		m.failed = false
+	}
+	m.mux.Unlock()
+
+	err := relay(ctx, m.Broker, events, messages, func(msg Message) Message {
+		m.mux.Lock()
+		m.lastMessage = time.Now()
+		m.mux.Unlock()
+
+		return msg
+	})
+
+	m.mux.Lock()
+	m.subscriptions--
+	if err != nil && ctx.Err() == nil {
+		m.failed = true
+	}
+	m.mux.Unlock()
+
+	return err
+}
+
+// Status checks the health of the broker and reports the state of its subscriptions
+func (m *Monitor) Status(ctx context.Context) Status {
+	var s Status
+	err := m.Health(ctx)
+
+	m.mux.Lock()
+	s.Subscriptions = m.subscriptions
+	s.Reconnects = m.reconnects
+	if !m.lastMessage.IsZero() {
+		age := time.Since(m.lastMessage)
+		s.LastMessageAge = new(float64)
+		*s.LastMessageAge = age.Seconds()
+
+		if err == nil && m.MaxIdle > 0 && age > m.MaxIdle {
+			err = errIdle
+		}
+	}
+	m.mux.Unlock()
+
+	if err == nil && s.Subscriptions == 0 {
+		err = errNotSubscribed
+	}
+
+	s.Healthy = err == nil
+	if err != nil {
+		s.Error = err.Error()
+	}
+	return s
+}
+
+// ServeHTTP writes the status of the broker as JSON
+func (m *Monitor) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s := m.Status(r.Context())
+
+	w.Header().Set("Content-Type", "application/json")
+	if !s.Healthy {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_ = json.NewEncoder(w).Encode(s)
+}
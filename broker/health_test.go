@@ -0,0 +1,80 @@
+package broker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// flakyBroker delivers its messages, then blocks until the context is done or fails with err
+type flakyBroker struct {
+	testBroker
+	err error
+}
+
+func (b *flakyBroker) Subscribe(ctx context.Context, events []string, messages chan<- Message) error {
+	_ = b.testBroker.Subscribe(ctx, events, messages)
+	if b.err != nil {
+		return b.err
+	}
+
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (b *flakyBroker) Health(ctx context.Context) error {
+	return b.err
+}
+
+func TestMonitor(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	b := &flakyBroker{testBroker: testBroker{{IOPacket: IOPacket{E: "foo"}}}, err: ErrDisconnected}
+	m := &Monitor{Broker: b, MaxIdle: time.Minute}
+
+	status := func() (int, Status) {
+		rec := httptest.NewRecorder()
+		m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		var s Status
+		assert.NoError(t, json.NewDecoder(rec.Body).Decode(&s))
+		return rec.Code, s
+	}
+
+	code, s := status()
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Nil(t, s.LastMessageAge)
+
+	msgs := make(chan Message, 1)
+	assert.True(t, errors.Is(m.Subscribe(ctx, []string{"foo"}, msgs), ErrDisconnected))
+	<-msgs
+
+	b.err = nil
+	go func() {
+		_ = m.Subscribe(ctx, []string{"foo"}, msgs)
+	}()
+	<-msgs
+
+	assert.Eventually(t, func() bool {
+		code, s = status()
+		return code == http.StatusOK
+	}, time.Second, 10*time.Millisecond)
+	assert.True(t, s.Healthy)
+	assert.Equal(t, 1, s.Subscriptions)
+	assert.Equal(t, 1, s.Reconnects)
+	if assert.NotNil(t, s.LastMessageAge) {
+		assert.Less(t, *s.LastMessageAge, time.Minute.Seconds())
+	}
+
+	m.MaxIdle = time.Nanosecond
+	code, s = status()
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, errIdle.Error(), s.Error)
+}
@@ -0,0 +1,110 @@
+package broker
+
+import (
+	"context"
+	"sync"
+)
+
+// InFlight tracks messages that have been delivered but not yet acknowledged, so that a broker can
+// wait for them to be handled when shutting down. The zero value is ready to use. Once shut down,
+// an InFlight cannot be reused.
+type InFlight struct {
+	mux     sync.Mutex
+	count   int
+	closed  bool
+	closing chan struct{}
+	drained chan struct{}
+}
+
+func (f *InFlight) init() {
+	if f.closing == nil {
+		f.closing = make(chan struct{})
+		f.drained = make(chan struct{})
+	}
+}
+
+// Add starts tracking a message. The returned function stops tracking it and may safely be called
+// more than once. If shutdown has already begun, the message is not tracked and should be released
+// back to the broker instead of delivered.
+func (f *InFlight) Add() (done func(), ok bool) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	f.init()
+	if f.closed {
+		return nil, false
+	}
+
+	f.count++
+
+	var once sync.Once
+	return func() {
+		once.Do(f.done)
+	}, true
+}
+
+func (f *InFlight) done() {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	f.count--
+	if f.closed && f.count == 0 {
+		close(f.drained)
+	}
+}
+
+// Closing returns a channel that is closed once shutdown begins
+func (f *InFlight) Closing() <-chan struct{} {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	f.init()
+	return f.closing
+}
+
+// Closed returns whether shutdown has begun
+func (f *InFlight) Closed() bool {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	return f.closed
+}
+
+// Context returns a copy of ctx that is also cancelled once shutdown begins
+func (f *InFlight) Context(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	closing := f.Closing()
+
+	go func() {
+		select {
+		case <-closing:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, cancel
+}
+
+// Shutdown begins shutdown and waits until every tracked message is done or the context is
+// cancelled
+func (f *InFlight) Shutdown(ctx context.Context) error {
+	f.mux.Lock()
+	f.init()
+	if !f.closed {
+		f.closed = true
+		close(f.closing)
+
+		if f.count == 0 {
+			close(f.drained)
+		}
+	}
+	f.mux.Unlock()
+
+	select {
+	case <-f.drained:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
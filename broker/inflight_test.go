@@ -0,0 +1,27 @@
+package broker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInFlightShutdown(t *testing.T) {
+	var f InFlight
+
+	done, ok := f.Add()
+	assert.True(t, ok)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, f.Shutdown(ctx), context.DeadlineExceeded)
+
+	_, ok = f.Add()
+	assert.False(t, ok)
+
+	done()
+	done()
+	assert.NoError(t, f.Shutdown(context.Background()))
+}
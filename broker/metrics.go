@@ -0,0 +1,47 @@
+package broker
+
+import "time"
+
+// Metrics receives measurements from brokers. Implementations must be safe for concurrent use.
+type Metrics interface {
+	// Published is called for every message successfully published
+	Published(event, group string)
+
+	// Consumed is called for every message delivered to a subscriber. Latency is the time since
+	// the message was published, or negative if the broker cannot tell.
+	Consumed(event, group string, latency time.Duration)
+
+	// Acked and Nacked are called for every message successfully acknowledged or nacked
+	Acked(event, group string)
+	Nacked(event, group string)
+
+	// Redelivered is called for every message that is delivered again after another consumer
+	// failed to acknowledge it
+	Redelivered(event, group string)
+
+	// Called is called with the round-trip time of every RPC call that received a response
+	Called(event, group string, duration time.Duration)
+
+	// DecodeFailed is called for every message whose body could not be decoded
+	DecodeFailed(event, group string)
+}
+
+// NopMetrics discards all measurements. Brokers use it when no Metrics are configured.
+type NopMetrics struct{}
+
+func (NopMetrics) Published(event, group string)                       {}
+func (NopMetrics) Consumed(event, group string, latency time.Duration) {}
+func (NopMetrics) Acked(event, group string)                           {}
+func (NopMetrics) Nacked(event, group string)                          {}
+func (NopMetrics) Redelivered(event, group string)                     {}
+func (NopMetrics) Called(event, group string, duration time.Duration)  {}
+func (NopMetrics) DecodeFailed(event, group string)                    {}
+
+// MetricsOrNop returns m, or NopMetrics if m is nil
+func MetricsOrNop(m Metrics) Metrics {
+	if m == nil {
+		return NopMetrics{}
+	}
+
+	return m
+}
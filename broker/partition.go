@@ -0,0 +1,34 @@
+package broker
+
+import (
+	"context"
+	"hash/fnv"
+)
+
+// PartitionedPublisher is implemented by brokers that can split an event into partitions. Messages
+// published with the same key always land in the same partition, so a consumer that owns the
+// partition receives them in order.
+type PartitionedPublisher interface {
+	PublishPartitioned(ctx context.Context, event, key string, data interface{}) error
+}
+
+// Partition returns the partition in [0, n) that a key belongs to
+func Partition(key string, n int) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(n))
+}
+
+// OwnedPartitions returns the partitions a consumer reads, which is every partition in [0, n) if
+// it doesn't own any in particular
+func OwnedPartitions(n int, owned []int) []int {
+	if len(owned) != 0 {
+		return owned
+	}
+
+	all := make([]int, n)
+	for i := range all {
+		all[i] = i
+	}
+	return all
+}
@@ -0,0 +1,21 @@
+package broker
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPartition(t *testing.T) {
+	for _, key := range []string{"", "81384788765712384", "222078108977594368"} {
+		p := Partition(key, 8)
+		assert.GreaterOrEqual(t, p, 0)
+		assert.Less(t, p, 8)
+		assert.Equal(t, p, Partition(key, 8))
+	}
+}
+
+func TestOwnedPartitions(t *testing.T) {
+	assert.Equal(t, []int{0, 1, 2}, OwnedPartitions(3, nil))
+	assert.Equal(t, []int{1}, OwnedPartitions(3, []int{1}))
+}
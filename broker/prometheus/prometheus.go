@@ -0,0 +1,121 @@
+package prometheus
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var labels = []string{"event", "group"}
+
+// Collector collects broker metrics for Prometheus. Set it as the Metrics of a broker and register
+// it with a Prometheus registry.
+type Collector struct {
+	published      *prometheus.CounterVec
+	consumed       *prometheus.CounterVec
+	latency        *prometheus.HistogramVec
+	acked          *prometheus.CounterVec
+	nacked         *prometheus.CounterVec
+	redelivered    *prometheus.CounterVec
+	calls          *prometheus.HistogramVec
+	decodeFailures *prometheus.CounterVec
+}
+
+// NewCollector makes a new collector whose metrics are prefixed with the given namespace
+func NewCollector(namespace string) *Collector {
+	counter := func(name, help string) *prometheus.CounterVec {
+		return prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "broker",
+			Name:      name,
+			Help:      help,
+		}, labels)
+	}
+
+	histogram := func(name, help string) *prometheus.HistogramVec {
+		return prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "broker",
+			Name:      name,
+			Help:      help,
+			Buckets:   prometheus.ExponentialBuckets(0.001, 4, 10),
+		}, labels)
+	}
+
+	return &Collector{
+		published:      counter("published_total", "Number of messages published."),
+		consumed:       counter("consumed_total", "Number of messages consumed."),
+		latency:        histogram("consume_latency_seconds", "Time between publishing and consuming a message, where known."),
+		acked:          counter("acked_total", "Number of messages acknowledged."),
+		nacked:         counter("nacked_total", "Number of messages nacked."),
+		redelivered:    counter("redelivered_total", "Number of messages delivered again after not being acknowledged."),
+		calls:          histogram("rpc_duration_seconds", "Round-trip time of RPC calls."),
+		decodeFailures: counter("decode_failures_total", "Number of messages whose body could not be decoded."),
+	}
+}
+
+func (c *Collector) collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		c.published,
+		c.consumed,
+		c.latency,
+		c.acked,
+		c.nacked,
+		c.redelivered,
+		c.calls,
+		c.decodeFailures,
+	}
+}
+
+// Describe implements prometheus.Collector
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, collector := range c.collectors() {
+		collector.Describe(ch)
+	}
+}
+
+// Collect implements prometheus.Collector
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	for _, collector := range c.collectors() {
+		collector.Collect(ch)
+	}
+}
+
+// Published implements broker.Metrics
+func (c *Collector) Published(event, group string) {
+	c.published.WithLabelValues(event, group).Inc()
+}
+
+// Consumed implements broker.Metrics. Messages with an unknown latency are counted without being
+// observed in the latency histogram.
+func (c *Collector) Consumed(event, group string, latency time.Duration) {
+	c.consumed.WithLabelValues(event, group).Inc()
+	if latency >= 0 {
+		c.latency.WithLabelValues(event, group).Observe(latency.Seconds())
+	}
+}
+
+// Acked implements broker.Metrics
+func (c *Collector) Acked(event, group string) {
+	c.acked.WithLabelValues(event, group).Inc()
+}
+
+// Nacked implements broker.Metrics
+func (c *Collector) Nacked(event, group string) {
+	c.nacked.WithLabelValues(event, group).Inc()
+}
+
+// Redelivered implements broker.Metrics
+func (c *Collector) Redelivered(event, group string) {
+	c.redelivered.WithLabelValues(event, group).Inc()
+}
+
+// Called implements broker.Metrics
+func (c *Collector) Called(event, group string, duration time.Duration) {
+	c.calls.WithLabelValues(event, group).Observe(duration.Seconds())
+}
+
+// DecodeFailed implements broker.Metrics
+func (c *Collector) DecodeFailed(event, group string) {
+	c.decodeFailures.WithLabelValues(event, group).Inc()
+}
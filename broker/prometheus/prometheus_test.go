@@ -0,0 +1,44 @@
+package prometheus
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/spec-tacles/go/broker"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollector(t *testing.T) {
+	ctx := context.Background()
+	c := NewCollector("test")
+
+	r, w := io.Pipe()
+	b := broker.ManagedRWBroker{RWBroker: broker.RWBroker{R: r, W: w}, Metrics: c}
+	msgs := make(chan broker.Message)
+
+	go func() {
+		_ = b.Subscribe(ctx, []string{"foo"}, msgs)
+	}()
+
+	published := make(chan error)
+	go func() {
+		published <- b.Publish(ctx, "foo", "bar")
+	}()
+
+	msg := <-msgs
+	assert.NoError(t, msg.Ack(ctx))
+	assert.NoError(t, <-published)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(c.published.WithLabelValues("foo", "")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(c.acked.WithLabelValues("foo", "")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(c.consumed.WithLabelValues("foo", "")))
+
+	// RW brokers do not know when packets were published
+	assert.Equal(t, 0, testutil.CollectAndCount(c.latency))
+
+	problems, err := testutil.CollectAndLint(c)
+	assert.NoError(t, err)
+	assert.Empty(t, problems)
+}
@@ -0,0 +1,132 @@
+package broker
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/ugorji/go/codec"
+)
+
+// Record is a message captured by a Recorder
+type Record struct {
+	Time  time.Time   `codec:"t"`
+	Event string      `codec:"e"`
+	Data  interface{} `codec:"d"`
+
+	// Received is true for messages the recorded broker received rather than published
+	Received bool `codec:"r,omitempty"`
+
+	// Headers holds metadata of received messages, such as their ID
+	Headers map[string]string `codec:"h,omitempty"`
+}
+
+// Recorder wraps a broker to append every message it publishes or receives to W, using the same
+// msgpack framing as RWBroker. Recording stops at the first error writing to W, which Err returns.
+type Recorder struct {
+	Broker
+	W io.Writer
+
+	mux     sync.Mutex
+	encoder *codec.Encoder
+	err     error
+}
+
+// Err returns the error that stopped the recording, if any
+func (r *Recorder) Err() error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	return r.err
+}
+
+func (r *Recorder) record(rec Record) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if r.err != nil {
+		return
+	}
+	if r.encoder == nil {
+		r.encoder = codec.NewEncoder(r.W, &codecHandle)
+	}
+
+	r.err = r.encoder.Encode(rec)
+}
+
+// Publish publishes data and records it if that succeeded
+func (r *Recorder) Publish(ctx context.Context, event string, data interface{}) error {
+	err := r.Broker.Publish(ctx, event, data)
+	if err == nil {
+		r.record(Record{Time: time.Now(), Event: event, Data: data})
+	}
+	return err
+}
+
+// Subscribe implements Broker interface, recording every message before delivering it
+func (r *Recorder) Subscribe(ctx context.Context, events []string, messages chan<- Message) error {
+	return relay(ctx, r.Broker, events, messages, func(msg Message) Message {
+		rec := Record{Time: time.Now(), Event: msg.Event(), Data: msg.Body(), Received: true}
+		if id := MessageID(msg); id != "" {
+			rec.Headers = map[string]string{"id": id}
+		}
+
+		r.record(rec)
+		return msg
+	})
+}
+
+// Player republishes a recording made by a Recorder
+type Player struct {
+	R io.Reader
+
+	// Speed is how many times faster than recorded to play messages. Zero plays them at the
+	// original speed, and positive infinity plays them without waiting.
+	Speed float64
+
+	// Filter decides whether to play a record, if set
+	Filter func(rec *Record) bool
+}
+
+// Play publishes every record to the broker, keeping the time between records divided by Speed,
+// until the recording ends or the context is cancelled
+func (p *Player) Play(ctx context.Context, b Broker) error {
+	speed := p.Speed
+	if speed <= 0 {
+		speed = 1
+	}
+
+	decoder := codec.NewDecoder(p.R, &codecHandle)
+	var first time.Time
+	start := time.Now()
+
+	for {
+		var rec Record
+		if err := decoder.Decode(&rec); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		if p.Filter != nil && !p.Filter(&rec) {
+			continue
+		}
+
+		if first.IsZero() {
+			first = rec.Time
+		}
+
+		at := start.Add(time.Duration(float64(rec.Time.Sub(first)) / speed))
+		select {
+		case <-time.After(time.Until(at)):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		if err := b.Publish(ctx, rec.Event, rec.Data); err != nil {
+			return err
+		}
+	}
+}
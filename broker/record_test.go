@@ -0,0 +1,57 @@
+package broker
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecorder(t *testing.T) {
+	ctx := context.Background()
+	buf := &bytes.Buffer{}
+	received := &testMessage{IOPacket: IOPacket{E: "bar", D: "received"}, id: "1"}
+	r := &Recorder{Broker: testBroker{received}, W: buf}
+
+	assert.NoError(t, r.Publish(ctx, "foo", "published"))
+	time.Sleep(50 * time.Millisecond)
+
+	msgs := make(chan Message, 1)
+	assert.NoError(t, r.Subscribe(ctx, []string{"bar"}, msgs))
+	assert.Equal(t, received, <-msgs)
+	assert.NoError(t, r.Err())
+
+	var played []Record
+	target := &bridgeTarget{}
+	p := &Player{
+		R: bytes.NewReader(buf.Bytes()),
+		Filter: func(rec *Record) bool {
+			played = append(played, *rec)
+			return true
+		},
+	}
+
+	start := time.Now()
+	assert.NoError(t, p.Play(ctx, target))
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+	assert.Equal(t, []string{"foo", "bar"}, target.published)
+
+	if assert.Len(t, played, 2) {
+		assert.False(t, played[0].Received)
+		assert.EqualValues(t, "published", played[0].Data)
+		assert.True(t, played[1].Received)
+		assert.EqualValues(t, "received", played[1].Data)
+		assert.Equal(t, map[string]string{"id": "1"}, played[1].Headers)
+	}
+
+	target = &bridgeTarget{}
+	p = &Player{R: bytes.NewReader(buf.Bytes()), Speed: math.Inf(1)}
+
+	start = time.Now()
+	assert.NoError(t, p.Play(ctx, target))
+	assert.Less(t, time.Since(start), 50*time.Millisecond)
+	assert.Equal(t, []string{"foo", "bar"}, target.published)
+}
@@ -0,0 +1,56 @@
+package redis
+
+import (
+	"context"
+	"math/rand"
+
+	"github.com/mediocregopher/radix/v4"
+	"github.com/spec-tacles/go/broker"
+)
+
+// PublishBatch adds every message to its stream in a single pipeline. Ephemeral events are
+// published through PubSub one by one afterwards.
+func (r *Redis) PublishBatch(ctx context.Context, batch []broker.Envelope) error {
+	if r.actor == nil {
+		return broker.ErrDisconnected
+	}
+
+	pipeline := radix.NewPipeline()
+	var ephemeral, durable []broker.Envelope
+	for _, env := range batch {
+		if r.isEphemeral(env.Event) {
+			ephemeral = append(ephemeral, env)
+			continue
+		}
+
+		stream := env.Event
+		if r.Partitions > 0 {
+			stream = partitionStream(env.Event, rand.Intn(r.Partitions))
+		}
+
+		action, err := r.xadd(stream, env.Data, nil)
+		if err != nil {
+			return err
+		}
+
+		pipeline.Append(action)
+		durable = append(durable, env)
+	}
+
+	if len(durable) != 0 {
+		if err := r.actor.Do(ctx, pipeline); err != nil {
+			return err
+		}
+
+		for _, env := range durable {
+			r.metrics().Published(env.Event, r.Group)
+		}
+	}
+
+	for _, env := range ephemeral {
+		if err := r.PubSub.Publish(ctx, env.Event, env.Data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package redis
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/mediocregopher/radix/v4"
+	"github.com/spec-tacles/go/broker"
+)
+
+// claimDedup sets KEYS[1] to pending for ARGV[1] milliseconds unless it is set, returning 1 if it
+// was claimed, 2 if it was committed and 0 if it is pending
+var claimDedup = radix.NewEvalScript(`
+if redis.call('SET', KEYS[1], 'pending', 'NX', 'PX', ARGV[1]) then
+	return 1
+end
+if redis.call('GET', KEYS[1]) == 'committed' then
+	return 2
+end
+return 0
+`)
+
+// DedupStore is a broker.DedupStore that keeps keys in Redis with an expiry, so that claims and
+// the window are shared by every client
+type DedupStore struct {
+	actor  RedisActor
+	Prefix string
+}
+
+// NewDedupStore creates a new Redis dedup store whose keys start with prefix
+func NewDedupStore(actor RedisActor, prefix string) *DedupStore {
+	return &DedupStore{
+		actor:  actor,
+		Prefix: prefix,
+	}
+}
+
+// Claim implements broker.DedupStore
+func (s *DedupStore) Claim(ctx context.Context, key string, lease time.Duration) (claimed, committed bool, err error) {
+	if lease < time.Millisecond {
+		return false, false, broker.ErrDedupWindow
+	}
+
+	var res int
+	ttl := strconv.FormatInt(lease.Milliseconds(), 10)
+	if err = s.actor.Do(ctx, claimDedup.Cmd(&res, []string{s.Prefix + key}, ttl)); err != nil {
+		return
+	}
+
+	return res == 1, res == 2, nil
+}
+
+// Commit implements broker.DedupStore
+func (s *DedupStore) Commit(ctx context.Context, key string, window time.Duration) error {
+	if window < time.Millisecond {
+		return broker.ErrDedupWindow
+	}
+
+	ttl := strconv.FormatInt(window.Milliseconds(), 10)
+	return s.actor.Do(ctx, radix.Cmd(nil, "SET", s.Prefix+key, "committed", "PX", ttl))
+}
+
+// Release implements broker.DedupStore
+func (s *DedupStore) Release(ctx context.Context, key string) error {
+	return s.actor.Do(ctx, radix.Cmd(nil, "DEL", s.Prefix+key))
+}
@@ -0,0 +1,33 @@
+package redis
+
+import (
+	"context"
+
+	"github.com/mediocregopher/radix/v4"
+	"github.com/spec-tacles/go/broker"
+)
+
+// Health pings Redis, and checks PubSub if it is set
+func (r *Redis) Health(ctx context.Context) error {
+	if err := ping(ctx, r.actor); err != nil {
+		return err
+	}
+
+	if r.PubSub != nil {
+		return r.PubSub.Health(ctx)
+	}
+	return nil
+}
+
+// Health pings Redis
+func (p *PubSub) Health(ctx context.Context) error {
+	return ping(ctx, p.actor)
+}
+
+func ping(ctx context.Context, actor RedisActor) error {
+	if actor == nil {
+		return broker.ErrDisconnected
+	}
+
+	return actor.Do(ctx, radix.Cmd(nil, "PING"))
+}
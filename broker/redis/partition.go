@@ -0,0 +1,59 @@
+package redis
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/spec-tacles/go/broker"
+)
+
+// PublishPartitioned publishes a message to the partition of the event that key belongs to, such as
+// the ID of the guild the event is for. Since each partition is consumed by a single client,
+// messages with the same key are handled in the order they were published. Ephemeral events are
+// not partitioned.
+func (r *Redis) PublishPartitioned(ctx context.Context, event, key string, data interface{}) error {
+	if r.isEphemeral(event) {
+		return r.PubSub.Publish(ctx, event, data)
+	}
+
+	stream := event
+	if r.Partitions > 0 {
+		stream = partitionStream(event, broker.Partition(key, r.Partitions))
+	}
+
+	_, err := r.publish(ctx, event, stream, data)
+	return err
+}
+
+func partitionStream(event string, partition int) string {
+	return event + ":" + strconv.Itoa(partition)
+}
+
+// streams returns the streams this client consumes for the given events
+func (r *Redis) streams(events []string) []string {
+	if r.Partitions <= 0 {
+		return events
+	}
+
+	owned := broker.OwnedPartitions(r.Partitions, r.OwnedPartitions)
+	streams := make([]string, 0, len(events)*len(owned))
+	for _, event := range events {
+		for _, partition := range owned {
+			streams = append(streams, partitionStream(event, partition))
+		}
+	}
+	return streams
+}
+
+// streamEvent returns the event that a stream carries
+func (r *Redis) streamEvent(stream string) string {
+	if r.Partitions <= 0 {
+		return stream
+	}
+
+	if i := strings.LastIndexByte(stream, ':'); i >= 0 {
+		return stream[:i]
+	}
+	return stream
+}
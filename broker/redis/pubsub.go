@@ -0,0 +1,151 @@
+package redis
+
+import (
+	"context"
+
+	"github.com/mediocregopher/radix/v4"
+	"github.com/spec-tacles/go/broker"
+)
+
+// PubSubMessage represents a message received from the Redis pub/sub broker
+type PubSubMessage struct {
+	p     *PubSub
+	event string
+	body  []byte
+}
+
+// ID is always empty, since pub/sub messages have no IDs
+func (m *PubSubMessage) ID() string {
+	return ""
+}
+
+func (m *PubSubMessage) Event() string {
+	return m.event
+}
+
+// Body returns the body of the message, or nil if it could not be decoded
+func (m *PubSubMessage) Body() (data interface{}) {
+	_ = m.Decode(&data)
+	return
+}
+
+// Decode decodes the body of the message into v
+func (m *PubSubMessage) Decode(v interface{}) error {
+	err := broker.Decode(m.body, v)
+	if err != nil {
+		m.p.metrics().DecodeFailed(m.event, "")
+	}
+	return err
+}
+
+// Reply is unsupported, since pub/sub messages have no ID to reply to
+func (m *PubSubMessage) Reply(ctx context.Context, data interface{}) error {
+	return broker.ErrCannotReply
+}
+
+// Ack does nothing, since pub/sub messages are never redelivered
+func (m *PubSubMessage) Ack(ctx context.Context) error {
+	return nil
+}
+
+// Nack does nothing, since pub/sub messages are never redelivered
+func (m *PubSubMessage) Nack(ctx context.Context) error {
+	return nil
+}
+
+// PubSub is a broker that uses Redis pub/sub. Nothing is stored: a message only reaches the
+// clients subscribed at the time it is published. This suits high-volume events that are safe to
+// lose, such as typing starts and presence updates.
+type PubSub struct {
+	actor    RedisActor
+	network  string
+	addr     string
+	inFlight broker.InFlight
+
+	// Config is used to open the connection of each subscription
+	Config radix.PersistentPubSubConnConfig
+
+	// Metrics receives measurements from this broker, if set. Pub/sub has no groups, so the group
+	// label is always empty.
+	Metrics broker.Metrics
+}
+
+// NewPubSub creates a new pub/sub broker. Messages are published through actor, while each
+// subscription opens its own connection to the given address.
+func NewPubSub(actor RedisActor, network, addr string) *PubSub {
+	return &PubSub{
+		actor:   actor,
+		network: network,
+		addr:    addr,
+	}
+}
+
+// Publish publishes a message to every client currently subscribed to the event
+func (p *PubSub) Publish(ctx context.Context, event string, data interface{}) error {
+	if p.actor == nil {
+		return broker.ErrDisconnected
+	}
+
+	b, err := broker.Encode(data)
+	if err != nil {
+		return err
+	}
+
+	err = p.actor.Do(ctx, radix.Cmd(nil, "PUBLISH", event, string(b)))
+	if err == nil {
+		p.metrics().Published(event, "")
+	}
+	return err
+}
+
+func (p *PubSub) metrics() broker.Metrics {
+	return broker.MetricsOrNop(p.Metrics)
+}
+
+// Subscribe subscribes this broker to events until the context is cancelled. It returns nil once
+// the broker is shut down.
+func (p *PubSub) Subscribe(ctx context.Context, events []string, messages chan<- broker.Message) error {
+	ctx, cancel := p.inFlight.Context(ctx)
+	defer cancel()
+
+	err := p.subscribe(ctx, events, messages)
+	if p.inFlight.Closed() {
+		return nil
+	}
+
+	return err
+}
+
+// Shutdown stops every subscription. Pub/sub messages need no acknowledgement, so there is nothing
+// to wait for.
+func (p *PubSub) Shutdown(ctx context.Context) error {
+	return p.inFlight.Shutdown(ctx)
+}
+
+func (p *PubSub) subscribe(ctx context.Context, events []string, messages chan<- broker.Message) error {
+	conn, err := p.Config.New(ctx, func() (string, string, error) {
+		return p.network, p.addr, nil
+	})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err = conn.Subscribe(ctx, events...); err != nil {
+		return err
+	}
+
+	for {
+		msg, err := conn.Next(ctx)
+		if err != nil {
+			return err
+		}
+
+		p.metrics().Consumed(msg.Channel, "", -1)
+		select {
+		case messages <- &PubSubMessage{p: p, event: msg.Channel, body: msg.Message}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
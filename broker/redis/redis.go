@@ -9,53 +9,121 @@ import (
 	"time"
 
 	"github.com/mediocregopher/radix/v4"
+	"github.com/mediocregopher/radix/v4/resp"
 	"github.com/mediocregopher/radix/v4/resp/resp3"
 	"github.com/spec-tacles/go/broker"
 )
 
-const streamDataKey = "data"
+const (
+	streamDataKey     = "data"
+	streamEncodingKey = "encoding"
+	streamReplyKey    = "reply"
+)
 
 // RedisMessage represents a message received from the Redis broker
 type RedisMessage struct {
-	r     *Redis
-	id    radix.StreamEntryID
-	event string
-	body  string
+	r        *Redis
+	id       radix.StreamEntryID
+	event    string
+	stream   string
+	body     string
+	encoding string
+	replyTo  string
+	replay   bool
+	done     func()
 }
 
 type RedisActor interface {
 	Do(context.Context, radix.Action) error
 }
 
+// ID returns the stream entry ID of the message. Entry IDs are only unique within their stream,
+// so messages of partitioned events are identified by their stream and entry ID.
+func (m *RedisMessage) ID() string {
+	if m.stream != m.event {
+		return m.stream + "/" + m.id.String()
+	}
+	return m.id.String()
+}
+
 func (m *RedisMessage) Event() string {
 	return m.event
 }
 
-// Body returns the body of the message
+// Body returns the body of the message, or nil if it could not be decoded
 func (m *RedisMessage) Body() (data interface{}) {
-	_ = broker.Decode([]byte(m.body), &data)
+	_ = m.Decode(&data)
 	return
 }
 
-// Reply sends a RPC response back to the original client
+// Decode decompresses the body of the message and decodes it into v
+func (m *RedisMessage) Decode(v interface{}) error {
+	b, err := broker.Decompress([]byte(m.body), m.encoding)
+	if err == nil {
+		err = broker.Decode(b, v)
+	}
+	if err != nil {
+		m.r.metrics().DecodeFailed(m.event, m.r.Group)
+	}
+	return err
+}
+
+// Reply sends a RPC response back to the original client. The reply is published to the channel
+// named by the entry, or to the event followed by the entry ID if it names none.
 func (m *RedisMessage) Reply(ctx context.Context, data interface{}) error {
 	b, err := broker.Encode(data)
 	if err != nil {
 		return err
 	}
 
-	key := m.event + m.id.String()
+	key := m.replyTo
+	if key == "" {
+		key = m.event + m.id.String()
+	}
 	return m.r.actor.Do(ctx, radix.Cmd(nil, "PUBLISH", key, string(b)))
 }
 
-// Ack acknowledges receipt of the message
+// Ack acknowledges receipt of the message. Replayed messages are not part of any pending list, so
+// acknowledging them does nothing.
 func (m *RedisMessage) Ack(ctx context.Context) error {
-	return m.r.actor.Do(ctx, radix.Cmd(nil, "XACK", m.event, m.r.Group, m.id.String()))
+	defer m.finish()
+	if m.replay {
+		return nil
+	}
+
+	err := m.r.actor.Do(ctx, radix.Cmd(nil, "XACK", m.stream, m.r.Group, m.id.String()))
+	if err == nil {
+		m.r.metrics().Acked(m.event, m.r.Group)
+	}
+	return err
+}
+
+// Nack releases the message so that other clients can claim it right away instead of after
+// UnackTimeout. Replayed messages cannot be released, so this does nothing for them.
+func (m *RedisMessage) Nack(ctx context.Context) error {
+	defer m.finish()
+	if m.replay {
+		return nil
+	}
+
+	idle := strconv.FormatInt(m.r.UnackTimeout.Milliseconds(), 10)
+	err := m.r.actor.Do(ctx, radix.Cmd(nil, "XCLAIM", m.stream, m.r.Group, m.r.Name, "0", m.id.String(), "IDLE", idle, "JUSTID"))
+	if err == nil {
+		m.r.metrics().Nacked(m.event, m.r.Group)
+	}
+	return err
+}
+
+func (m *RedisMessage) finish() {
+	if m.done != nil {
+		m.done()
+	}
 }
 
 // Redis is a broker that uses Redis streams
 type Redis struct {
-	actor RedisActor
+	actor    RedisActor
+	inFlight broker.InFlight
 
 	Config        radix.PoolConfig
 	Group         string
@@ -63,6 +131,10 @@ type Redis struct {
 	MaxChunk      uint64
 	BlockInterval time.Duration
 
+	// Offset is where groups created by Subscribe start reading from. It has no effect on groups
+	// that already exist; use SetOffset to move those.
+	Offset Offset
+
 	// UnackTimeout is the amount of time a client is allowed to wait before acknowledging a stream
 	// item. In Redis terms, this is the amount of time an item is allowed to spend in the PEL
 	// before being claimed by another client.
@@ -72,6 +144,35 @@ type Redis struct {
 	// duration (relative to current server time) are evicted from the queue and not processed. This
 	// relies on your server time being somewhat synced with your Redis server.
 	PendingTimeout time.Duration
+
+	// PubSub carries the events in Ephemeral instead of streams. These events are not stored and are
+	// lost if nobody is subscribed when they are published.
+	PubSub    *PubSub
+	Ephemeral map[string]bool
+
+	// Partitions splits every durable event into this many streams. Zero disables partitioning.
+	// See PublishPartitioned.
+	Partitions int
+
+	// OwnedPartitions are the partitions this client consumes. Leave empty to consume all of them.
+	OwnedPartitions []int
+
+	// ScheduledKey is the sorted set holding messages published with PublishAt until they are due.
+	// It defaults to "<group>:scheduled", so clients scheduling messages for each other must share
+	// a group or set the same key.
+	ScheduledKey string
+
+	// ScheduleInterval is how often subscribed clients move due messages from ScheduledKey into
+	// their streams. Zero disables moving them, so set it on at least one subscriber if messages
+	// are scheduled.
+	ScheduleInterval time.Duration
+
+	// CompressThreshold is the encoded size in bytes from which message bodies stored in streams
+	// are compressed. Zero disables compression. Messages sent over pub/sub are never compressed.
+	CompressThreshold int
+
+	// Metrics receives measurements from this broker, if set
+	Metrics broker.Metrics
 }
 
 // NewRedis creates a new Redis broker
@@ -82,6 +183,7 @@ func NewRedis(actor RedisActor, group string) *Redis {
 		Group:          group,
 		Name:           strconv.FormatInt(rand.Int63(), 16),
 		MaxChunk:       10,
+		Offset:         OffsetOldest,
 		BlockInterval:  3 * time.Second,
 		UnackTimeout:   15 * time.Second,
 		PendingTimeout: 1 * time.Hour,
@@ -90,45 +192,154 @@ func NewRedis(actor RedisActor, group string) *Redis {
 
 // Publish publishes a message to the broker
 func (r *Redis) Publish(ctx context.Context, event string, data interface{}) error {
+	if r.isEphemeral(event) {
+		return r.PubSub.Publish(ctx, event, data)
+	}
+
+	stream := event
+	if r.Partitions > 0 {
+		stream = partitionStream(event, rand.Intn(r.Partitions))
+	}
+
+	_, err := r.publish(ctx, event, stream, data)
+	return err
+}
+
+// publish adds a message to a stream with any extra fields, returning its entry ID
+func (r *Redis) publish(ctx context.Context, event, stream string, data interface{}, fields ...string) (id string, err error) {
 	if r.actor == nil {
-		return broker.ErrDisconnected
+		return "", broker.ErrDisconnected
+	}
+
+	action, err := r.xadd(stream, data, &id, fields...)
+	if err != nil {
+		return "", err
 	}
 
+	err = r.actor.Do(ctx, action)
+	if err == nil {
+		r.metrics().Published(event, r.Group)
+	}
+	return
+}
+
+// xadd returns the command that adds a message to a stream with any extra fields, storing its
+// entry ID in rcv
+func (r *Redis) xadd(stream string, data interface{}, rcv interface{}, fields ...string) (radix.Action, error) {
+	b, encoding, err := r.encode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{stream}
+	if minID := r.minID(); minID != "" {
+		args = append(args, "MINID", "~", minID)
+	}
+	args = append(args, "*", streamDataKey, string(b))
+	if encoding != "" {
+		args = append(args, streamEncodingKey, encoding)
+	}
+	args = append(args, fields...)
+
+	return radix.Cmd(rcv, "XADD", args...), nil
+}
+
+// encode encodes data for a stream, compressing it according to CompressThreshold
+func (r *Redis) encode(data interface{}) ([]byte, string, error) {
 	b, err := broker.Encode(data)
 	if err != nil {
-		return err
+		return nil, "", err
 	}
 
-	var action radix.Action
-	if r.UnackTimeout != 0 {
-		minTime := strconv.FormatInt(time.Now().Add(-r.PendingTimeout).UnixMilli(), 10)
-		action = radix.Cmd(
-			nil,
-			"XADD", event,
-			"MINID", "~", minTime,
-			"*",
-			streamDataKey, string(b),
-		)
-	} else {
-		action = radix.Cmd(
-			nil,
-			"XADD", event,
-			"*",
-			streamDataKey, string(b),
-		)
+	return broker.Compress(b, r.CompressThreshold)
+}
+
+// minID returns the ID before which stream entries are trimmed, or an empty string if they aren't
+func (r *Redis) minID() string {
+	if r.UnackTimeout == 0 {
+		return ""
 	}
 
-	return r.actor.Do(ctx, action)
+	return strconv.FormatInt(time.Now().Add(-r.PendingTimeout).UnixMilli(), 10)
+}
+
+func (r *Redis) metrics() broker.Metrics {
+	return broker.MetricsOrNop(r.Metrics)
 }
 
-// Subscribe subscribes this broker to an event
+// Subscribe subscribes this broker to an event. It returns nil once the broker is shut down.
 func (r *Redis) Subscribe(ctx context.Context, events []string, messages chan<- broker.Message) error {
+	ctx, cancel := r.inFlight.Context(ctx)
+	defer cancel()
+
+	err := r.subscribe(ctx, events, messages)
+	if r.inFlight.Closed() {
+		return nil
+	}
+
+	return err
+}
+
+// Shutdown stops fetching new entries and waits for delivered messages to be acknowledged or
+// nacked. Entries that were fetched but not yet delivered are released so that other clients can
+// claim them right away.
+func (r *Redis) Shutdown(ctx context.Context) error {
+	return r.inFlight.Shutdown(ctx)
+}
+
+func (r *Redis) subscribe(ctx context.Context, events []string, messages chan<- broker.Message) error {
+	var durable, ephemeral []string
+	for _, event := range events {
+		if r.isEphemeral(event) {
+			ephemeral = append(ephemeral, event)
+		} else {
+			durable = append(durable, event)
+		}
+	}
+
+	if len(ephemeral) == 0 {
+		return r.subscribeStreams(ctx, durable, messages)
+	}
+
+	if len(durable) == 0 {
+		return r.PubSub.Subscribe(ctx, ephemeral, messages)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errs := make(chan error, 2)
+	go func() {
+		errs <- r.subscribeStreams(ctx, durable, messages)
+	}()
+
+	go func() {
+		errs <- r.PubSub.Subscribe(ctx, ephemeral, messages)
+	}()
+
+	err := <-errs
+	cancel()
+	<-errs
+	return err
+}
+
+func (r *Redis) isEphemeral(event string) bool {
+	return r.PubSub != nil && r.Ephemeral[event]
+}
+
+func (r *Redis) subscribeStreams(ctx context.Context, events []string, messages chan<- broker.Message) error {
 	if r.actor == nil {
 		return broker.ErrDisconnected
 	}
 
-	for _, event := range events {
-		err := r.actor.Do(ctx, radix.Cmd(nil, "XGROUP", "CREATE", event, r.Group, "0", "MKSTREAM"))
+	offset := r.Offset
+	if offset == "" {
+		offset = OffsetOldest
+	}
+
+	streams := r.streams(events)
+	for _, stream := range streams {
+		err := r.actor.Do(ctx, radix.Cmd(nil, "XGROUP", "CREATE", stream, r.Group, string(offset), "MKSTREAM"))
 
 		var redisError resp3.SimpleError
 		if errors.As(err, &redisError) && strings.HasPrefix(redisError.S, "BUSYGROUP") {
@@ -140,39 +351,54 @@ func (r *Redis) Subscribe(ctx context.Context, events []string, messages chan<-
 		}
 	}
 
-	return r.listen(ctx, events, messages)[0]
+	return r.listen(ctx, streams, messages)
 }
 
-func (r *Redis) listen(ctx context.Context, events []string, messages chan<- broker.Message) [2]error {
+// listen reads the streams until one of its loops fails, then stops the others and returns the
+// first error
+func (r *Redis) listen(ctx context.Context, streams []string, messages chan<- broker.Message) error {
 	var cancel context.CancelFunc
 	ctx, cancel = context.WithCancel(ctx)
 	defer cancel()
 
-	errs := make(chan error)
-	defer close(errs)
-
-	go func() {
-		errs <- r.listenXread(ctx, events, messages)
-	}()
+	loops := []func(context.Context) error{
+		func(ctx context.Context) error {
+			return r.listenXread(ctx, streams, messages)
+		},
+		func(ctx context.Context) error {
+			return r.listenXautoclaim(ctx, streams, messages)
+		},
+	}
+	if r.ScheduleInterval > 0 {
+		loops = append(loops, r.listenScheduled)
+	}
 
-	go func() {
-		errs <- r.listenXautoclaim(ctx, events, messages)
-	}()
+	errs := make(chan error, len(loops))
+	for _, loop := range loops {
+		go func(loop func(context.Context) error) {
+			errs <- loop(ctx)
+		}(loop)
+	}
 
-	return [2]error{<-errs, <-errs}
+	err := <-errs
+	cancel()
+	for i := 1; i < len(loops); i++ {
+		<-errs
+	}
+	return err
 }
 
-func (r *Redis) listenXread(ctx context.Context, events []string, messages chan<- broker.Message) (err error) {
+func (r *Redis) listenXread(ctx context.Context, streams []string, messages chan<- broker.Message) (err error) {
 	var (
 		data      []radix.StreamEntries
 		streamIds []string
 	)
 
 	for {
-		eventCount := len(events)
-		if len(streamIds) != eventCount {
-			streamIds = make([]string, eventCount)
-			for i := 0; i < eventCount; i++ {
+		streamCount := len(streams)
+		if len(streamIds) != streamCount {
+			streamIds = make([]string, streamCount)
+			for i := 0; i < streamCount; i++ {
 				streamIds[i] = ">"
 			}
 		}
@@ -181,7 +407,7 @@ func (r *Redis) listenXread(ctx context.Context, events []string, messages chan<
 			"GROUP", r.Group, r.Name,
 			"COUNT", strconv.FormatUint(r.MaxChunk, 10),
 			"BLOCK", strconv.FormatInt(r.BlockInterval.Milliseconds(), 10),
-			"STREAMS", events, streamIds,
+			"STREAMS", streams, streamIds,
 		)
 		err = r.actor.Do(ctx, action)
 
@@ -190,48 +416,236 @@ func (r *Redis) listenXread(ctx context.Context, events []string, messages chan<
 		}
 
 		for _, entry := range data {
-			r.handleData(&entry.Entries, entry.Stream, messages)
+			r.handleData(ctx, &entry.Entries, entry.Stream, false, messages)
 		}
 	}
 }
 
-func (r *Redis) listenXautoclaim(ctx context.Context, events []string, messages chan<- broker.Message) (err error) {
-	var data radix.StreamEntries
+func (r *Redis) listenXautoclaim(ctx context.Context, streams []string, messages chan<- broker.Message) (err error) {
+	// each stream keeps its own cursor so that a large backlog in one stream is paged through
+	// without starving the others
+	cursors := make(map[string]string, len(streams))
+	for _, stream := range streams {
+		cursors[stream] = autoclaimStart
+	}
 
 	for {
 		timeout := strconv.FormatInt(r.UnackTimeout.Milliseconds(), 10)
-		start := "0-0"
+		count := strconv.FormatUint(r.MaxChunk, 10)
+		more := false
 
-		for _, event := range events {
-			action := radix.Cmd(&data, "XAUTOCLAIM", event, r.Group, r.Name, timeout, start)
+		for _, stream := range streams {
+			var data autoclaimReply
+			start := cursors[stream]
+			action := radix.Cmd(&data, "XAUTOCLAIM", stream, r.Group, r.Name, timeout, start, "COUNT", count)
 			err = r.actor.Do(ctx, action)
 
 			if err != nil {
 				return
 			}
 
-			start = data.Stream
-			r.handleData(&data.Entries, event, messages)
+			if data.Nulls > 0 {
+				if err = r.ackDeleted(ctx, stream, start, data.Cursor, data.Nulls); err != nil {
+					return
+				}
+			}
+
+			cursors[stream] = data.Cursor
+			if data.Cursor != autoclaimStart {
+				more = true
+			}
+
+			for range data.Entries {
+				r.metrics().Redelivered(r.streamEvent(stream), r.Group)
+			}
+
+			r.handleData(ctx, &data.Entries, stream, false, messages)
 		}
 
-		time.Sleep(r.BlockInterval)
+		// only wait once every stream has been paged through completely
+		if more {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(r.BlockInterval):
+		}
 	}
 }
 
-func (r *Redis) handleData(data *[]radix.StreamEntry, event string, msgs chan<- broker.Message) {
-	for _, entry := range *data {
-		for _, v := range entry.Fields {
-			k, v := v[0], v[1]
-			if k != streamDataKey {
+const autoclaimStart = "0-0"
+
+// autoclaimReply is the reply to XAUTOCLAIM. Entries that were deleted while pending are returned
+// as nil by Redis 6.2, which are counted in Nulls and left for ackDeleted; Redis 7 removes them
+// from the PEL itself and lists their IDs in Deleted.
+type autoclaimReply struct {
+	Cursor  string
+	Entries []radix.StreamEntry
+	Deleted []string
+	Nulls   int
+}
+
+// UnmarshalRESP implements resp.Unmarshaler
+func (a *autoclaimReply) UnmarshalRESP(br resp.BufferedReader, o *resp.Opts) error {
+	var ah resp3.ArrayHeader
+	if err := ah.UnmarshalRESP(br, o); err != nil {
+		return err
+	}
+
+	if ah.NumElems != 2 && ah.NumElems != 3 {
+		return errors.New("invalid xautoclaim response")
+	}
+
+	var cursor resp3.BlobString
+	if err := cursor.UnmarshalRESP(br, o); err != nil {
+		return err
+	}
+	a.Cursor = cursor.S
+
+	var entries resp3.ArrayHeader
+	if err := entries.UnmarshalRESP(br, o); err != nil {
+		return err
+	}
+
+	a.Entries = make([]radix.StreamEntry, 0, entries.NumElems)
+	a.Nulls = 0
+	for i := 0; i < entries.NumElems; i++ {
+		if deleted, err := nextIsNull(br); err != nil {
+			return err
+		} else if deleted {
+			if err := (&resp3.Null{}).UnmarshalRESP(br, o); err != nil {
+				return err
+			}
+			a.Nulls++
+			continue
+		}
+
+		var entry radix.StreamEntry
+		if err := entry.UnmarshalRESP(br, o); err != nil {
+			return err
+		}
+		a.Entries = append(a.Entries, entry)
+	}
+
+	a.Deleted = a.Deleted[:0]
+	if ah.NumElems == 3 {
+		return resp3.Unmarshal(br, &a.Deleted, o)
+	}
+
+	return nil
+}
+
+// ackDeleted acknowledges up to count entries from start to end that this client has pending but
+// that no longer exist in the stream. XAUTOCLAIM on Redis 6.2 claims these without removing them
+// from the PEL, so they would otherwise be claimed again on every pass.
+func (r *Redis) ackDeleted(ctx context.Context, stream, start, end string, count int) error {
+	if end == autoclaimStart {
+		end = "+"
+	}
+
+	page := strconv.FormatUint(r.MaxChunk, 10)
+	for count > 0 {
+		// each pending entry is its ID, consumer, idle time and delivery count
+		var pending [][]string
+		err := r.actor.Do(ctx, radix.Cmd(&pending, "XPENDING", stream, r.Group, start, end, page, r.Name))
+		if err != nil || len(pending) == 0 {
+			return err
+		}
+
+		for _, entry := range pending {
+			var entries []radix.StreamEntry
+			if err = r.actor.Do(ctx, radix.Cmd(&entries, "XRANGE", stream, entry[0], entry[0])); err != nil {
+				return err
+			}
+			if len(entries) > 0 {
 				continue
 			}
 
-			msgs <- &RedisMessage{
-				r:     r,
-				event: event,
-				body:  v,
-				id:    entry.ID,
+			if err = r.actor.Do(ctx, radix.Cmd(nil, "XACK", stream, r.Group, entry[0])); err != nil {
+				return err
 			}
+			count--
+		}
+
+		// continue right after the last entry
+		last := strings.SplitN(pending[len(pending)-1][0], "-", 2)
+		if len(last) != 2 {
+			return errors.New("invalid xpending response")
+		}
+
+		seq, err := strconv.ParseUint(last[1], 10, 64)
+		if err != nil {
+			return err
+		}
+		start = last[0] + "-" + strconv.FormatUint(seq+1, 10)
+	}
+
+	return nil
+}
+
+func nextIsNull(br resp.BufferedReader) (bool, error) {
+	if ok, err := resp3.NextMessageIs(br, resp3.NullPrefix); ok || err != nil {
+		return ok, err
+	}
+
+	b, err := br.Peek(3)
+	if err != nil {
+		return false, err
+	}
+
+	// RESP2 null arrays
+	return string(b) == "*-1", nil
+}
+
+func (r *Redis) handleData(ctx context.Context, data *[]radix.StreamEntry, stream string, replay bool, msgs chan<- broker.Message) {
+	event := r.streamEvent(stream)
+	for _, entry := range *data {
+		var body, encoding, replyTo string
+		var found bool
+		for _, field := range entry.Fields {
+			switch field[0] {
+			case streamDataKey:
+				body, found = field[1], true
+			case streamEncodingKey:
+				encoding = field[1]
+			case streamReplyKey:
+				replyTo = field[1]
+			}
+		}
+		if !found {
+			continue
+		}
+
+		msg := &RedisMessage{
+			r:        r,
+			event:    event,
+			stream:   stream,
+			body:     body,
+			encoding: encoding,
+			replyTo:  replyTo,
+			id:       entry.ID,
+			replay:   replay,
+		}
+
+		if !replay {
+			var ok bool
+			if msg.done, ok = r.inFlight.Add(); !ok {
+				_ = msg.Nack(context.Background())
+				continue
+			}
+
+			published := time.UnixMilli(int64(entry.ID.Time))
+			r.metrics().Consumed(event, r.Group, time.Since(published))
+		}
+
+		select {
+		case msgs <- msg:
+		case <-r.inFlight.Closing():
+			_ = msg.Nack(context.Background())
+		case <-ctx.Done():
+			_ = msg.Nack(context.Background())
 		}
 	}
 }
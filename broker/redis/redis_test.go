@@ -2,6 +2,7 @@ package redis
 
 import (
 	"context"
+	"strings"
 	"testing"
 	"time"
 
@@ -118,3 +119,391 @@ func TestAutoclaim(t *testing.T) {
 	assert.NoError(t, msg.Ack(ctx))
 	assert.EqualValues(t, "bar", msg.Body())
 }
+
+func TestReplay(t *testing.T) {
+	connect()
+
+	ctx := context.Background()
+	start := time.Now()
+
+	err := r.Publish(ctx, "replayed", "bar")
+	assert.NoError(t, err)
+
+	msgs := make(chan broker.Message, 1)
+	err = r.Replay(ctx, []string{"replayed"}, start, time.Time{}, msgs)
+	assert.NoError(t, err)
+
+	msg := <-msgs
+	assert.NoError(t, msg.Ack(ctx))
+	assert.Equal(t, "replayed", msg.Event())
+	assert.EqualValues(t, "bar", msg.Body())
+}
+
+func TestAutoclaimReply(t *testing.T) {
+	ctx := context.Background()
+	conn := radix.NewStubConn("", "", func(context.Context, []string) interface{} {
+		return []interface{}{
+			"1-0",
+			[]interface{}{
+				[]interface{}{"0-1", []string{streamDataKey, "bar"}},
+				nil,
+			},
+			[]string{"0-2"},
+		}
+	})
+
+	var data autoclaimReply
+	err := conn.Do(ctx, radix.Cmd(&data, "XAUTOCLAIM", "foo", "test", "test", "0", "0-0"))
+	assert.NoError(t, err)
+	assert.Equal(t, "1-0", data.Cursor)
+	assert.Len(t, data.Entries, 1)
+	assert.Equal(t, "0-1", data.Entries[0].ID.String())
+	assert.Equal(t, []string{"0-2"}, data.Deleted)
+	assert.Equal(t, 1, data.Nulls)
+}
+
+func TestMessageID(t *testing.T) {
+	id := radix.StreamEntryID{Time: 1, Seq: 0}
+	assert.Equal(t, "1-0", (&RedisMessage{event: "foo", stream: "foo", id: id}).ID())
+
+	// entries of different partitions can have the same ID
+	first := &RedisMessage{event: "foo", stream: partitionStream("foo", 0), id: id}
+	second := &RedisMessage{event: "foo", stream: partitionStream("foo", 1), id: id}
+	assert.NotEqual(t, first.ID(), second.ID())
+}
+
+func TestReplyChannel(t *testing.T) {
+	ctx := context.Background()
+
+	var channels []string
+	conn := radix.NewStubConn("", "", func(_ context.Context, args []string) interface{} {
+		channels = append(channels, args[1])
+		return 1
+	})
+
+	r := &Redis{actor: conn}
+	id := radix.StreamEntryID{Time: 1, Seq: 2}
+	requested := &RedisMessage{r: r, event: "foo", stream: "foo:1", id: id, replyTo: "foo:reply:abc"}
+	assert.NoError(t, requested.Reply(ctx, "bar"))
+
+	// entries that name no channel are replied to on the event followed by the entry ID
+	legacy := &RedisMessage{r: r, event: "foo", stream: "foo", id: id}
+	assert.NoError(t, legacy.Reply(ctx, "bar"))
+
+	assert.Equal(t, []string{"foo:reply:abc", "foo1-2"}, channels)
+}
+
+func TestAckDeleted(t *testing.T) {
+	ctx := context.Background()
+	pending := []string{"0-1", "0-2", "0-3", "0-4"}
+	deleted := map[string]bool{"0-2": true, "0-4": true}
+
+	var acked []string
+	conn := radix.NewStubConn("", "", func(_ context.Context, args []string) interface{} {
+		switch args[0] {
+		case "XPENDING":
+			var page [][]string
+			for _, id := range pending {
+				if id >= args[3] && len(page) < 2 {
+					page = append(page, []string{id, args[6], "0", "1"})
+				}
+			}
+			return page
+		case "XRANGE":
+			if deleted[args[2]] {
+				return []interface{}{}
+			}
+			return []interface{}{[]interface{}{args[2], []string{streamDataKey, "bar"}}}
+		case "XACK":
+			acked = append(acked, args[3])
+			return 1
+		}
+		return nil
+	})
+
+	client := NewRedis(conn, "test")
+	client.MaxChunk = 2
+	assert.NoError(t, client.ackDeleted(ctx, "foo", autoclaimStart, autoclaimStart, 2))
+	assert.Equal(t, []string{"0-2", "0-4"}, acked)
+}
+
+func TestEphemeral(t *testing.T) {
+	connect()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client, err := radix.PoolConfig{}.New(ctx, "tcp", "localhost:6379")
+	assert.NoError(t, err)
+
+	ephemeral := NewRedis(client, "test")
+	ephemeral.PubSub = NewPubSub(client, "tcp", "localhost:6379")
+	ephemeral.Ephemeral = map[string]bool{"typing": true}
+
+	msgs := make(chan broker.Message)
+	go func() {
+		err := ephemeral.Subscribe(ctx, []string{"typing", "foo"}, msgs)
+		assert.ErrorIs(t, err, context.Canceled)
+	}()
+
+	// pub/sub only delivers to current subscribers, so wait for the subscription to be made
+	time.Sleep(100 * time.Millisecond)
+
+	err = ephemeral.Publish(ctx, "typing", "bar")
+	assert.NoError(t, err)
+
+	msg := <-msgs
+	assert.NoError(t, msg.Ack(ctx))
+	assert.Equal(t, "typing", msg.Event())
+	assert.EqualValues(t, "bar", msg.Body())
+
+	var length int
+	err = client.Do(ctx, radix.Cmd(&length, "XLEN", "typing"))
+	assert.NoError(t, err)
+	assert.Zero(t, length)
+}
+
+func TestShutdown(t *testing.T) {
+	connect()
+
+	ctx := context.Background()
+	client, err := radix.PoolConfig{}.New(ctx, "tcp", "localhost:6379")
+	assert.NoError(t, err)
+
+	shutdownRedis := NewRedis(client, "test")
+	msgs := make(chan broker.Message)
+
+	subscribed := make(chan error)
+	go func() {
+		subscribed <- shutdownRedis.Subscribe(ctx, []string{"shutdown"}, msgs)
+	}()
+
+	err = r.Publish(ctx, "shutdown", "bar")
+	assert.NoError(t, err)
+
+	msg := <-msgs
+	shutdown := make(chan error)
+	go func() {
+		shutdown <- shutdownRedis.Shutdown(ctx)
+	}()
+
+	assert.NoError(t, <-subscribed)
+	assert.NoError(t, msg.Ack(ctx))
+	assert.NoError(t, <-shutdown)
+}
+
+func TestPartitioned(t *testing.T) {
+	connect()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client, err := radix.PoolConfig{}.New(ctx, "tcp", "localhost:6379")
+	assert.NoError(t, err)
+
+	partitioned := NewRedis(client, "test")
+	partitioned.Partitions = 4
+
+	msgs := make(chan broker.Message)
+	go func() {
+		err := partitioned.Subscribe(ctx, []string{"partitioned"}, msgs)
+		assert.ErrorIs(t, err, context.Canceled)
+	}()
+
+	for i := 0; i < 10; i++ {
+		err = partitioned.PublishPartitioned(ctx, "partitioned", "81384788765712384", i)
+		assert.NoError(t, err)
+	}
+
+	for i := 0; i < 10; i++ {
+		msg := <-msgs
+		assert.NoError(t, msg.Ack(ctx))
+		assert.Equal(t, "partitioned", msg.Event())
+		assert.EqualValues(t, i, msg.Body())
+	}
+}
+
+func TestDedupStore(t *testing.T) {
+	connect()
+
+	ctx := context.Background()
+	client, err := radix.PoolConfig{}.New(ctx, "tcp", "localhost:6379")
+	assert.NoError(t, err)
+
+	s := NewDedupStore(client, "dedup:")
+	assert.NoError(t, client.Do(ctx, radix.Cmd(nil, "DEL", "dedup:foo")))
+
+	claimed, _, err := s.Claim(ctx, "foo", time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, claimed)
+
+	claimed, committed, err := s.Claim(ctx, "foo", time.Minute)
+	assert.NoError(t, err)
+	assert.False(t, claimed)
+	assert.False(t, committed)
+
+	assert.NoError(t, s.Commit(ctx, "foo", time.Minute))
+	claimed, committed, err = s.Claim(ctx, "foo", time.Minute)
+	assert.NoError(t, err)
+	assert.False(t, claimed)
+	assert.True(t, committed)
+
+	assert.NoError(t, s.Release(ctx, "foo"))
+	claimed, _, err = s.Claim(ctx, "foo", time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, claimed)
+
+	assert.ErrorIs(t, s.Commit(ctx, "foo", 0), broker.ErrDedupWindow)
+}
+
+func TestPublishAfter(t *testing.T) {
+	connect()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client, err := radix.PoolConfig{}.New(ctx, "tcp", "localhost:6379")
+	assert.NoError(t, err)
+
+	scheduled := NewRedis(client, "test")
+	scheduled.ScheduleInterval = 10 * time.Millisecond
+
+	msgs := make(chan broker.Message)
+	go func() {
+		err := scheduled.Subscribe(ctx, []string{"scheduled"}, msgs)
+		assert.ErrorIs(t, err, context.Canceled)
+	}()
+
+	start := time.Now()
+	err = scheduled.PublishAfter(ctx, 100*time.Millisecond, "scheduled", "bar")
+	assert.NoError(t, err)
+
+	msg := <-msgs
+	assert.NoError(t, msg.Ack(ctx))
+	assert.EqualValues(t, "bar", msg.Body())
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+}
+
+func TestCompression(t *testing.T) {
+	connect()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client, err := radix.PoolConfig{}.New(ctx, "tcp", "localhost:6379")
+	assert.NoError(t, err)
+
+	compressed := NewRedis(client, "test")
+	compressed.CompressThreshold = 64
+
+	msgs := make(chan broker.Message)
+	go func() {
+		err := compressed.Subscribe(ctx, []string{"compressed"}, msgs)
+		assert.ErrorIs(t, err, context.Canceled)
+	}()
+
+	data := strings.Repeat("foo", 1000)
+	assert.NoError(t, compressed.Publish(ctx, "compressed", data))
+
+	msg := <-msgs
+	assert.NoError(t, msg.Ack(ctx))
+	assert.EqualValues(t, data, msg.Body())
+}
+
+func TestRequest(t *testing.T) {
+	connect()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client, err := radix.PoolConfig{}.New(ctx, "tcp", "localhost:6379")
+	assert.NoError(t, err)
+
+	rpc := NewRedis(client, "test")
+	rpc.PubSub = NewPubSub(client, "tcp", "localhost:6379")
+
+	msgs := make(chan broker.Message)
+	go func() {
+		err := rpc.Subscribe(ctx, []string{"request"}, msgs)
+		assert.ErrorIs(t, err, context.Canceled)
+	}()
+
+	go func() {
+		msg := <-msgs
+		assert.NoError(t, msg.Reply(ctx, "pong"))
+		assert.NoError(t, msg.Ack(ctx))
+	}()
+
+	reply, err := rpc.Request(ctx, "request", "ping")
+	assert.NoError(t, err)
+	assert.EqualValues(t, "pong", reply)
+}
+
+func TestPublishBatch(t *testing.T) {
+	connect()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	msgs := make(chan broker.Message)
+	go func() {
+		err := r.Subscribe(ctx, []string{"batch"}, msgs)
+		assert.ErrorIs(t, err, context.Canceled)
+	}()
+
+	err := r.PublishBatch(ctx, []broker.Envelope{
+		{Event: "batch", Data: "first"},
+		{Event: "batch", Data: "second"},
+	})
+	assert.NoError(t, err)
+
+	for _, data := range []string{"first", "second"} {
+		msg := <-msgs
+		assert.NoError(t, msg.Ack(ctx))
+		assert.EqualValues(t, data, msg.Body())
+	}
+}
+
+func benchmarkBatch(size int) []broker.Envelope {
+	batch := make([]broker.Envelope, size)
+	for i := range batch {
+		batch[i] = broker.Envelope{Event: "benchmark", Data: map[string]interface{}{"content": "hello"}}
+	}
+	return batch
+}
+
+func BenchmarkPublish(b *testing.B) {
+	connect()
+
+	ctx := context.Background()
+	batch := benchmarkBatch(100)
+	for i := 0; i < b.N; i++ {
+		for _, env := range batch {
+			if err := r.Publish(ctx, env.Event, env.Data); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+	b.ReportMetric(float64(b.N*len(batch))/b.Elapsed().Seconds(), "msgs/s")
+}
+
+func BenchmarkPublishBatch(b *testing.B) {
+	connect()
+
+	ctx := context.Background()
+	batch := benchmarkBatch(100)
+	for i := 0; i < b.N; i++ {
+		if err := r.PublishBatch(ctx, batch); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(b.N*len(batch))/b.Elapsed().Seconds(), "msgs/s")
+}
+
+func TestHealth(t *testing.T) {
+	connect()
+
+	ctx := context.Background()
+	assert.NoError(t, r.Health(ctx))
+	assert.ErrorIs(t, NewRedis(nil, "test").Health(ctx), broker.ErrDisconnected)
+}
@@ -0,0 +1,98 @@
+package redis
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/mediocregopher/radix/v4"
+	"github.com/spec-tacles/go/broker"
+)
+
+// Offset represents the last delivered ID of a consumer group. Reading resumes with the first entry
+// after the offset.
+type Offset string
+
+const (
+	// OffsetOldest starts a group at the beginning of the stream
+	OffsetOldest Offset = "0"
+
+	// OffsetLatest starts a group at the end of the stream, so only new entries are delivered
+	OffsetLatest Offset = "$"
+)
+
+// OffsetID makes an offset that resumes reading after the entry with the given ID
+func OffsetID(id radix.StreamEntryID) Offset {
+	return Offset(id.String())
+}
+
+// OffsetTime makes an offset that resumes reading with the first entry added at or after t
+func OffsetTime(t time.Time) Offset {
+	return OffsetID(timeID(t).Prev())
+}
+
+func timeID(t time.Time) radix.StreamEntryID {
+	return radix.StreamEntryID{Time: uint64(t.UnixMilli())}
+}
+
+// SetOffset moves the group of this broker to the given offset for each event. Entries that were
+// already pending remain pending.
+func (r *Redis) SetOffset(ctx context.Context, events []string, offset Offset) error {
+	if r.actor == nil {
+		return broker.ErrDisconnected
+	}
+
+	for _, stream := range r.streams(events) {
+		err := r.actor.Do(ctx, radix.Cmd(nil, "XGROUP", "SETID", stream, r.Group, string(offset)))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Replay sends every entry of the given events that was added between from and to (inclusive) to
+// messages. A zero from or to leaves that end of the window open. Replaying reads the streams
+// directly, so group offsets and pending lists are untouched and acknowledging a replayed message
+// does nothing.
+func (r *Redis) Replay(ctx context.Context, events []string, from, to time.Time, messages chan<- broker.Message) error {
+	if r.actor == nil {
+		return broker.ErrDisconnected
+	}
+
+	ctx, cancel := r.inFlight.Context(ctx)
+	defer cancel()
+
+	end := "+"
+	if !to.IsZero() {
+		end = strconv.FormatUint(uint64(to.UnixMilli()), 10)
+	}
+
+	for _, stream := range r.streams(events) {
+		start := "-"
+		if !from.IsZero() {
+			start = timeID(from).String()
+		}
+
+		for {
+			var entries []radix.StreamEntry
+			action := radix.Cmd(&entries, "XRANGE", stream, start, end, "COUNT", strconv.FormatUint(r.MaxChunk, 10))
+			if err := r.actor.Do(ctx, action); err != nil {
+				if r.inFlight.Closed() {
+					return nil
+				}
+				return err
+			}
+
+			r.handleData(ctx, &entries, stream, true, messages)
+			if len(entries) == 0 || uint64(len(entries)) < r.MaxChunk {
+				break
+			}
+
+			start = entries[len(entries)-1].ID.Next().String()
+		}
+	}
+
+	return nil
+}
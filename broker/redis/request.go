@@ -0,0 +1,65 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/spec-tacles/go/broker"
+)
+
+// ErrNoPubSub occurs when making a request without PubSub, which carries the replies
+var ErrNoPubSub = errors.New("requests need pub/sub to receive replies")
+
+// Request publishes data and waits for the reply to it, implementing broker.Requester. Replies
+// arrive over pub/sub, so PubSub must be set; each request opens its own connection to receive its
+// reply. Ephemeral events cannot be replied to.
+func (r *Redis) Request(ctx context.Context, event string, data interface{}) (interface{}, error) {
+	if r.PubSub == nil {
+		return nil, ErrNoPubSub
+	}
+	if r.isEphemeral(event) {
+		return nil, broker.ErrCannotReply
+	}
+
+	conn, err := r.PubSub.Config.New(ctx, func() (string, string, error) {
+		return r.PubSub.network, r.PubSub.addr, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	// the entry names a channel unique to this request for the reply to be published to
+	channel := event + ":reply:" + uuid.New().String()
+	if err = conn.Subscribe(ctx, channel); err != nil {
+		return nil, err
+	}
+
+	stream := event
+	if r.Partitions > 0 {
+		stream = partitionStream(event, rand.Intn(r.Partitions))
+	}
+
+	start := time.Now()
+	if _, err = r.publish(ctx, event, stream, data, streamReplyKey, channel); err != nil {
+		return nil, err
+	}
+
+	for {
+		msg, err := conn.Next(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		if msg.Channel == channel {
+			r.metrics().Called(event, r.Group, time.Since(start))
+
+			var reply interface{}
+			err = broker.Decode(msg.Message, &reply)
+			return reply, err
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package redis
+
+import (
+	"context"
+	"math/rand"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mediocregopher/radix/v4"
+	"github.com/spec-tacles/go/broker"
+)
+
+// Scheduled messages are stored as "<mode><target>\n<nonce>[ <encoding>]\n<data>" so that the
+// script can move them without decoding anything. The nonce keeps identical messages from replacing
+// each other, and the encoding is only present for compressed messages.
+const (
+	scheduleStream = "x"
+	schedulePubSub = "p"
+)
+
+// moveScheduled moves up to ARGV[2] messages due by ARGV[1] from the sorted set to their targets,
+// trimming streams to ARGV[3] if it is set
+var moveScheduled = radix.NewEvalScript(`
+local due = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, ARGV[2])
+for _, member in ipairs(due) do
+	local mode, target, nonce, data = string.match(member, '^(.)([^\n]*)\n([^\n]*)\n(.*)$')
+	local encoding = string.match(nonce, ' (.*)$')
+	if mode == 'p' then
+		redis.call('PUBLISH', target, data)
+	else
+		local args = {target}
+		if ARGV[3] ~= '' then
+			table.insert(args, 'MINID')
+			table.insert(args, '~')
+			table.insert(args, ARGV[3])
+		end
+		table.insert(args, '*')
+		table.insert(args, '` + streamDataKey + `')
+		table.insert(args, data)
+		if encoding then
+			table.insert(args, '` + streamEncodingKey + `')
+			table.insert(args, encoding)
+		end
+		redis.call('XADD', unpack(args))
+	end
+	redis.call('ZREM', KEYS[1], member)
+end
+return #due
+`)
+
+// PublishAt publishes a message once the given time has passed. Until then it is kept in
+// ScheduledKey, from where subscribed clients with a ScheduleInterval move it into its stream.
+func (r *Redis) PublishAt(ctx context.Context, t time.Time, event string, data interface{}) error {
+	if r.actor == nil {
+		return broker.ErrDisconnected
+	}
+
+	mode, target := scheduleStream, event
+	if r.isEphemeral(event) {
+		mode = schedulePubSub
+	} else if r.Partitions > 0 {
+		target = partitionStream(event, rand.Intn(r.Partitions))
+	}
+
+	var (
+		b        []byte
+		encoding string
+		err      error
+	)
+	if mode == schedulePubSub {
+		b, err = broker.Encode(data)
+	} else {
+		b, encoding, err = r.encode(data)
+	}
+	if err != nil {
+		return err
+	}
+
+	nonce := uuid.New().String()
+	if encoding != "" {
+		nonce += " " + encoding
+	}
+
+	member := mode + target + "\n" + nonce + "\n" + string(b)
+	score := strconv.FormatInt(t.UnixMilli(), 10)
+	return r.actor.Do(ctx, radix.Cmd(nil, "ZADD", r.scheduledKey(), score, member))
+}
+
+// PublishAfter publishes a message once the given duration has passed
+func (r *Redis) PublishAfter(ctx context.Context, d time.Duration, event string, data interface{}) error {
+	return r.PublishAt(ctx, time.Now().Add(d), event, data)
+}
+
+func (r *Redis) scheduledKey() string {
+	if r.ScheduledKey == "" {
+		return r.Group + ":scheduled"
+	}
+
+	return r.ScheduledKey
+}
+
+func (r *Redis) listenScheduled(ctx context.Context) error {
+	for {
+		var moved uint64
+		now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+		count := strconv.FormatUint(r.MaxChunk, 10)
+
+		action := moveScheduled.Cmd(&moved, []string{r.scheduledKey()}, now, count, r.minID())
+		if err := r.actor.Do(ctx, action); err != nil {
+			return err
+		}
+
+		// keep going while there may be more due messages
+		if moved != 0 && moved == r.MaxChunk {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(r.ScheduleInterval):
+		}
+	}
+}
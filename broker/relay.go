@@ -0,0 +1,36 @@
+package broker
+
+import "context"
+
+// relay subscribes to b and passes every message it receives to handle, which returns the message
+// to deliver or nil to drop it. Delivery gives up once the context is done, and messages that are
+// not delivered are nacked so that the subscription can end. It returns the error that ended the
+// subscription.
+func relay(ctx context.Context, b Broker, events []string, messages chan<- Message, handle func(msg Message) Message) error {
+	received := make(chan Message)
+	errs := make(chan error, 1)
+
+	go func() {
+		errs <- b.Subscribe(ctx, events, received)
+		close(received)
+	}()
+
+	for msg := range received {
+		if ctx.Err() != nil {
+			_ = Nack(context.Background(), msg)
+			continue
+		}
+
+		if msg = handle(msg); msg == nil {
+			continue
+		}
+
+		select {
+		case messages <- msg:
+		case <-ctx.Done():
+			_ = Nack(context.Background(), msg)
+		}
+	}
+
+	return <-errs
+}
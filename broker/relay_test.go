@@ -0,0 +1,31 @@
+package broker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRelayCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	first := &bridgeMessage{testMessage: testMessage{IOPacket: IOPacket{E: "foo"}}}
+	second := &bridgeMessage{testMessage: testMessage{IOPacket: IOPacket{E: "foo"}}}
+
+	handled := make(chan struct{})
+	go func() {
+		<-handled
+		cancel()
+	}()
+
+	// nobody receives the messages, so relaying them gives up once the context is cancelled
+	err := relay(ctx, bridgeSource{first, second}, []string{"foo"}, make(chan Message), func(msg Message) Message {
+		if msg == first {
+			close(handled)
+		}
+		return msg
+	})
+	assert.NoError(t, err)
+	assert.True(t, first.nacked)
+	assert.True(t, second.nacked)
+}
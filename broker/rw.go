@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"io"
+	"sync"
+	"time"
 
 	"github.com/ugorji/go/codec"
 )
@@ -16,6 +18,9 @@ type RWBroker struct {
 
 var ErrCannotReply = errors.New("cannot reply")
 
+// errShutdown stops reading once a managed broker is shut down
+var errShutdown = errors.New("shut down")
+
 // IOPacket represents a JSON packet transmitted through an RW broker
 type IOPacket struct {
 	E string      `codec:"event"`
@@ -43,8 +48,35 @@ func (b *RWBroker) Publish(ctx context.Context, event string, data interface{})
 	return codec.NewEncoder(b.W, &codecHandle).Encode(IOPacket{event, data})
 }
 
+// PublishBatch encodes every message first and writes them to the writer at once
+func (b *RWBroker) PublishBatch(ctx context.Context, batch []Envelope) error {
+	var buf []byte
+	encoder := codec.NewEncoderBytes(&buf, &codecHandle)
+	for _, env := range batch {
+		if err := encoder.Encode(IOPacket{env.Event, env.Data}); err != nil {
+			return err
+		}
+	}
+
+	_, err := b.W.Write(buf)
+	return err
+}
+
 // Subscribe implements Broker interface
-func (b *RWBroker) Subscribe(ctx context.Context, events []string, messages chan<- Message) (err error) {
+func (b *RWBroker) Subscribe(ctx context.Context, events []string, messages chan<- Message) error {
+	return b.read(events, nil, func(pk *IOPacket) error {
+		select {
+		case messages <- pk:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+}
+
+// read decodes packets from the reader and handles those of the given events until decoding or
+// handling fails. Decoding errors other than EOF are passed to failed, if set.
+func (b *RWBroker) read(events []string, failed func(pk *IOPacket), handle func(pk *IOPacket) error) error {
 	eMap := make(map[string]struct{}, len(events))
 	for _, event := range events {
 		eMap[event] = struct{}{}
@@ -53,12 +85,133 @@ func (b *RWBroker) Subscribe(ctx context.Context, events []string, messages chan
 	decoder := codec.NewDecoder(b.R, &codecHandle)
 	for {
 		pk := &IOPacket{}
-		if err = decoder.Decode(pk); err != nil {
-			return
+		if err := decoder.Decode(pk); err != nil {
+			if err != io.EOF && failed != nil {
+				failed(pk)
+			}
+			return err
 		}
 
-		if _, ok := eMap[pk.E]; ok {
-			messages <- pk
+		if _, ok := eMap[pk.E]; !ok {
+			continue
+		}
+		if err := handle(pk); err != nil {
+			return err
 		}
 	}
 }
+
+// ManagedRWBroker is an RWBroker that can be shared between goroutines. It serializes writes,
+// publishes scheduled packets, reports metrics and shuts down gracefully.
+type ManagedRWBroker struct {
+	RWBroker
+
+	// Metrics receives measurements from this broker, if set. RW brokers have no groups, so the
+	// group label is always empty.
+	Metrics Metrics
+
+	inFlight  InFlight
+	writing   sync.Mutex
+	scheduled timers
+}
+
+// managedPacket is a packet delivered by a ManagedRWBroker
+type managedPacket struct {
+	*IOPacket
+	metrics Metrics
+	done    func()
+}
+
+func (p *managedPacket) Ack(context.Context) error {
+	p.done()
+	MetricsOrNop(p.metrics).Acked(p.E, "")
+	return nil
+}
+
+// Nack only marks the packet as handled, since packets cannot be redelivered
+func (p *managedPacket) Nack(context.Context) error {
+	p.done()
+	MetricsOrNop(p.metrics).Nacked(p.E, "")
+	return nil
+}
+
+// Publish writes data to the writer
+func (b *ManagedRWBroker) Publish(ctx context.Context, event string, data interface{}) error {
+	b.writing.Lock()
+	defer b.writing.Unlock()
+
+	err := b.RWBroker.Publish(ctx, event, data)
+	if err == nil {
+		MetricsOrNop(b.Metrics).Published(event, "")
+	}
+	return err
+}
+
+// PublishBatch encodes every message first and writes them to the writer at once
+func (b *ManagedRWBroker) PublishBatch(ctx context.Context, batch []Envelope) error {
+	b.writing.Lock()
+	defer b.writing.Unlock()
+
+	if err := b.RWBroker.PublishBatch(ctx, batch); err != nil {
+		return err
+	}
+
+	metrics := MetricsOrNop(b.Metrics)
+	for _, env := range batch {
+		metrics.Published(env.Event, "")
+	}
+	return nil
+}
+
+// PublishAt writes data to the writer once the given time has passed. Scheduled packets only live
+// in memory, and errors writing them are discarded.
+func (b *ManagedRWBroker) PublishAt(ctx context.Context, t time.Time, event string, data interface{}) error {
+	b.scheduled.add(t, func() {
+		_ = b.Publish(context.Background(), event, data)
+	})
+	return nil
+}
+
+// PublishAfter writes data to the writer once the given duration has passed
+func (b *ManagedRWBroker) PublishAfter(ctx context.Context, d time.Duration, event string, data interface{}) error {
+	return b.PublishAt(ctx, time.Now().Add(d), event, data)
+}
+
+// Subscribe implements Broker interface. It returns nil once the broker is shut down and the packet
+// currently being read has arrived.
+func (b *ManagedRWBroker) Subscribe(ctx context.Context, events []string, messages chan<- Message) error {
+	metrics := MetricsOrNop(b.Metrics)
+	failed := func(pk *IOPacket) {
+		metrics.DecodeFailed(pk.E, "")
+	}
+
+	err := b.read(events, failed, func(pk *IOPacket) error {
+		done, ok := b.inFlight.Add()
+		if !ok {
+			return errShutdown
+		}
+
+		metrics.Consumed(pk.E, "", -1)
+
+		select {
+		case messages <- &managedPacket{pk, b.Metrics, done}:
+			return nil
+		case <-b.inFlight.Closing():
+			done()
+			return errShutdown
+		case <-ctx.Done():
+			done()
+			return ctx.Err()
+		}
+	})
+
+	if err == errShutdown {
+		return nil
+	}
+	return err
+}
+
+// Shutdown stops delivering packets and waits for delivered ones to be acknowledged or nacked
+func (b *ManagedRWBroker) Shutdown(ctx context.Context) error {
+	return b.inFlight.Shutdown(ctx)
+}
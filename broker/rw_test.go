@@ -3,7 +3,10 @@ package broker
 import (
 	"context"
 	"io"
+	"io/ioutil"
+	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -27,3 +30,121 @@ func TestRWSubscribe(t *testing.T) {
 	assert.Equal(t, "foo", res.Event())
 	assert.EqualValues(t, "bar", res.Body())
 }
+
+func TestRWShutdown(t *testing.T) {
+	ctx := context.Background()
+	r, w := io.Pipe()
+	b := ManagedRWBroker{RWBroker: RWBroker{r, w}}
+	msgs := make(chan Message)
+
+	subscribed := make(chan error)
+	go func() {
+		subscribed <- b.Subscribe(ctx, []string{"foo"}, msgs)
+	}()
+
+	go func() {
+		assert.NoError(t, b.Publish(ctx, "foo", "bar"))
+		assert.NoError(t, b.Publish(ctx, "foo", "bar"))
+	}()
+
+	msg := <-msgs
+	shutdown := make(chan error)
+	go func() {
+		shutdown <- b.Shutdown(ctx)
+	}()
+
+	assert.NoError(t, <-subscribed)
+	select {
+	case <-shutdown:
+		assert.FailNow(t, "shutdown before the message was acknowledged")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	assert.NoError(t, msg.Ack(ctx))
+	assert.NoError(t, <-shutdown)
+}
+
+func TestRWPublishAfter(t *testing.T) {
+	ctx := context.Background()
+	r, w := io.Pipe()
+	b := ManagedRWBroker{RWBroker: RWBroker{r, w}}
+	msgs := make(chan Message)
+
+	go func() {
+		_ = b.Subscribe(ctx, []string{"foo"}, msgs)
+	}()
+
+	start := time.Now()
+	assert.NoError(t, b.PublishAfter(ctx, 40*time.Millisecond, "foo", "second"))
+	assert.NoError(t, b.PublishAfter(ctx, 20*time.Millisecond, "foo", "first"))
+
+	assert.EqualValues(t, "first", (<-msgs).Body())
+	assert.EqualValues(t, "second", (<-msgs).Body())
+	assert.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+}
+
+func TestRWPublishBatch(t *testing.T) {
+	ctx := context.Background()
+	r, w := io.Pipe()
+	b := RWBroker{r, w}
+	msgs := make(chan Message)
+
+	go func() {
+		_ = b.Subscribe(ctx, []string{"foo", "bar"}, msgs)
+	}()
+
+	go func() {
+		assert.NoError(t, b.PublishBatch(ctx, []Envelope{
+			{Event: "foo", Data: "first"},
+			{Event: "bar", Data: "second"},
+		}))
+	}()
+
+	msg := <-msgs
+	assert.Equal(t, "foo", msg.Event())
+	assert.EqualValues(t, "first", msg.Body())
+
+	msg = <-msgs
+	assert.Equal(t, "bar", msg.Event())
+	assert.EqualValues(t, "second", msg.Body())
+}
+
+func benchmarkRWPublish(b *testing.B, size int, publish func(*RWBroker, []Envelope) error) {
+	f, err := ioutil.TempFile("", "rw")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	batch := make([]Envelope, size)
+	for i := range batch {
+		batch[i] = Envelope{Event: "MESSAGE_CREATE", Data: map[string]interface{}{"content": "hello"}}
+	}
+
+	broker := &RWBroker{W: f}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := publish(broker, batch); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(b.N*size)/b.Elapsed().Seconds(), "msgs/s")
+}
+
+func BenchmarkRWPublish(b *testing.B) {
+	benchmarkRWPublish(b, 100, func(broker *RWBroker, batch []Envelope) error {
+		for _, env := range batch {
+			if err := broker.Publish(context.Background(), env.Event, env.Data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func BenchmarkRWPublishBatch(b *testing.B) {
+	benchmarkRWPublish(b, 100, func(broker *RWBroker, batch []Envelope) error {
+		return broker.PublishBatch(context.Background(), batch)
+	})
+}
@@ -0,0 +1,76 @@
+package broker
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+	"time"
+)
+
+// Scheduler is implemented by brokers that can publish messages at a later time
+type Scheduler interface {
+	PublishAt(ctx context.Context, t time.Time, event string, data interface{}) error
+	PublishAfter(ctx context.Context, d time.Duration, event string, data interface{}) error
+}
+
+type timer struct {
+	at time.Time
+	fn func()
+}
+
+type timerHeap []timer
+
+func (h timerHeap) Len() int            { return len(h) }
+func (h timerHeap) Less(i, j int) bool  { return h[i].at.Before(h[j].at) }
+func (h timerHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *timerHeap) Push(x interface{}) { *h = append(*h, x.(timer)) }
+func (h *timerHeap) Pop() interface{} {
+	old := *h
+	t := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return t
+}
+
+// timers runs functions at scheduled times using a single Go timer for the earliest of them
+type timers struct {
+	mux   sync.Mutex
+	heap  timerHeap
+	timer *time.Timer
+}
+
+func (t *timers) add(at time.Time, fn func()) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
+	heap.Push(&t.heap, timer{at, fn})
+	t.reset()
+}
+
+// reset points the timer at the earliest function. Callers must hold the lock.
+func (t *timers) reset() {
+	if len(t.heap) == 0 {
+		return
+	}
+
+	wait := time.Until(t.heap[0].at)
+	if t.timer == nil {
+		t.timer = time.AfterFunc(wait, t.run)
+	} else {
+		t.timer.Reset(wait)
+	}
+}
+
+func (t *timers) run() {
+	t.mux.Lock()
+	var due []func()
+	now := time.Now()
+	for len(t.heap) != 0 && !t.heap[0].at.After(now) {
+		due = append(due, heap.Pop(&t.heap).(timer).fn)
+	}
+	t.reset()
+	t.mux.Unlock()
+
+	for _, fn := range due {
+		fn()
+	}
+}
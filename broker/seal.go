@@ -0,0 +1,349 @@
+package broker
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"sync"
+	"time"
+)
+
+// DefaultSealMaxAge is how long after being sealed messages can be opened, unless the MaxAge of
+// the keyring is set. It matches the default time Redis entries are kept for.
+const DefaultSealMaxAge = time.Hour
+
+var (
+	// ErrUnknownKey occurs when a message was sealed with a key that is not in the keyring
+	ErrUnknownKey = errors.New("unknown key")
+
+	// ErrBadSignature occurs when the signature of a message does not match its contents
+	ErrBadSignature = errors.New("bad signature")
+
+	// ErrMalformedEnvelope occurs when a message body is not a sealed envelope
+	ErrMalformedEnvelope = errors.New("malformed envelope")
+
+	// ErrExpired occurs when a message was sealed longer ago than the maximum age of the keyring
+	ErrExpired = errors.New("sealed message expired")
+
+	// ErrInvalidKey occurs when a key has no ID or signing secret, or an encryption key that is not
+	// 16, 24 or 32 bytes long
+	ErrInvalidKey = errors.New("invalid key")
+)
+
+// SealError occurs when a sealed message cannot be opened, which means it was tampered with or
+// was not sealed by a trusted publisher. Err is one of ErrUnknownKey, ErrBadSignature,
+// ErrMalformedEnvelope or ErrExpired, or the error that decrypting or decoding it returned.
+type SealError struct {
+	Event string
+	KeyID string
+	Err   error
+}
+
+func (e *SealError) Error() string {
+	return "cannot open message for event " + e.Event + ": " + e.Err.Error()
+}
+
+func (e *SealError) Unwrap() error {
+	return e.Err
+}
+
+// Key is a secret shared between publishers and consumers of sealed messages
+type Key struct {
+	// ID identifies the key in every message sealed with it
+	ID string
+
+	// Sign is the HMAC-SHA256 key used to sign messages. It must not be empty, since anyone can
+	// sign with an empty key.
+	Sign []byte
+
+	// Encrypt is the AES key of 16, 24 or 32 bytes used to encrypt messages. Leave it empty to
+	// only sign them.
+	Encrypt []byte
+}
+
+func (key Key) validate() error {
+	if key.ID == "" || len(key.Sign) == 0 {
+		return ErrInvalidKey
+	}
+
+	switch len(key.Encrypt) {
+	case 0, 16, 24, 32:
+		return nil
+	default:
+		return ErrInvalidKey
+	}
+}
+
+// Keyring holds the keys that open messages and the one that seals them. To rotate keys without
+// rejecting messages, Add the new key on every consumer, then Use it on every publisher, and only
+// Remove the old key once its messages have been consumed.
+type Keyring struct {
+	mux     sync.RWMutex
+	current string
+	keys    map[string]Key
+
+	// MaxAge is how long after being sealed messages can be opened, which limits how long a
+	// captured message can be replayed for. Zero uses DefaultSealMaxAge, and a negative age accepts
+	// messages of any age.
+	MaxAge time.Duration
+}
+
+// NewKeyring makes a keyring that seals messages with the given key and opens messages sealed with
+// it or any of the old keys. It returns ErrInvalidKey if any of the keys is invalid.
+func NewKeyring(current Key, old ...Key) (*Keyring, error) {
+	k := &Keyring{current: current.ID, keys: make(map[string]Key, len(old)+1)}
+	for _, key := range append(old, current) {
+		if err := key.validate(); err != nil {
+			return nil, err
+		}
+		k.keys[key.ID] = key
+	}
+	return k, nil
+}
+
+// Add adds a key that opens messages, replacing any key with the same ID. It returns ErrInvalidKey
+// if the key is invalid.
+func (k *Keyring) Add(key Key) error {
+	if err := key.validate(); err != nil {
+		return err
+	}
+
+	k.mux.Lock()
+	defer k.mux.Unlock()
+
+	k.keys[key.ID] = key
+	return nil
+}
+
+// Use seals messages with the key of the given ID from now on
+func (k *Keyring) Use(id string) error {
+	k.mux.Lock()
+	defer k.mux.Unlock()
+
+	if _, ok := k.keys[id]; !ok {
+		return ErrUnknownKey
+	}
+
+	k.current = id
+	return nil
+}
+
+// Remove stops opening messages sealed with the key of the given ID. The key in use cannot be
+// removed.
+func (k *Keyring) Remove(id string) {
+	k.mux.Lock()
+	defer k.mux.Unlock()
+
+	if id != k.current {
+		delete(k.keys, id)
+	}
+}
+
+func (k *Keyring) get(id string) (Key, bool) {
+	k.mux.RLock()
+	defer k.mux.RUnlock()
+
+	key, ok := k.keys[id]
+	return key, ok
+}
+
+func (k *Keyring) use() Key {
+	k.mux.RLock()
+	defer k.mux.RUnlock()
+
+	return k.keys[k.current]
+}
+
+// envelope is the body of a sealed message. T is the time it was sealed in Unix milliseconds.
+type envelope struct {
+	K string `codec:"k"`
+	T int64  `codec:"t"`
+	N []byte `codec:"n,omitempty"`
+	D []byte `codec:"d"`
+	S []byte `codec:"s"`
+}
+
+// Seal encodes data and seals it with the key in use. The event and the time are part of the
+// signature, so the result cannot be passed off as another event or opened after the maximum age.
+func (k *Keyring) Seal(event string, data interface{}) ([]byte, error) {
+	key := k.use()
+	b, err := Encode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	env := envelope{K: key.ID, T: time.Now().UnixMilli(), D: b}
+	if len(key.Encrypt) != 0 {
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, err
+		}
+
+		env.N = make([]byte, aead.NonceSize())
+		if _, err := rand.Read(env.N); err != nil {
+			return nil, err
+		}
+		env.D = aead.Seal(nil, env.N, b, []byte(event))
+	}
+
+	env.S = signature(key, event, &env)
+	return Encode(env)
+}
+
+// Open verifies data sealed for the given event and decodes it into v. It returns a *SealError if
+// the data cannot be opened.
+func (k *Keyring) Open(event string, data []byte, v interface{}) error {
+	var env envelope
+	if err := Decode(data, &env); err != nil || env.K == "" {
+		return &SealError{Event: event, Err: ErrMalformedEnvelope}
+	}
+
+	fail := func(err error) error {
+		return &SealError{Event: event, KeyID: env.K, Err: err}
+	}
+
+	key, ok := k.get(env.K)
+	if !ok {
+		return fail(ErrUnknownKey)
+	}
+
+	if !hmac.Equal(env.S, signature(key, event, &env)) {
+		return fail(ErrBadSignature)
+	}
+
+	if maxAge := k.maxAge(); maxAge >= 0 && time.Since(time.UnixMilli(env.T)) > maxAge {
+		return fail(ErrExpired)
+	}
+
+	b := env.D
+	if len(key.Encrypt) != 0 {
+		aead, err := newAEAD(key)
+		if err != nil {
+			return fail(err)
+		}
+		if len(env.N) != aead.NonceSize() {
+			return fail(ErrMalformedEnvelope)
+		}
+
+		if b, err = aead.Open(nil, env.N, env.D, []byte(event)); err != nil {
+			return fail(err)
+		}
+	}
+
+	if err := Decode(b, v); err != nil {
+		return fail(err)
+	}
+	return nil
+}
+
+func (k *Keyring) maxAge() time.Duration {
+	if k.MaxAge == 0 {
+		return DefaultSealMaxAge
+	}
+	return k.MaxAge
+}
+
+func newAEAD(key Key) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key.Encrypt)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+func signature(key Key, event string, env *envelope) []byte {
+	var sealed [8]byte
+	binary.BigEndian.PutUint64(sealed[:], uint64(env.T))
+
+	mac := hmac.New(sha256.New, key.Sign)
+	for _, part := range [][]byte{[]byte(event), []byte(env.K), sealed[:], env.N, env.D} {
+		var size [4]byte
+		binary.BigEndian.PutUint32(size[:], uint32(len(part)))
+
+		mac.Write(size[:])
+		mac.Write(part)
+	}
+	return mac.Sum(nil)
+}
+
+// Sealed wraps a broker to sign, and optionally encrypt, the messages it publishes and to verify
+// the messages it receives, for brokers that untrusted clients can access. Replies are sealed for
+// the event of the message they reply to.
+//
+// Sealed should wrap other wrappers such as Dedup rather than the other way around, so that the
+// messages it delivers can be opened with Decode.
+type Sealed struct {
+	Broker
+	Keys *Keyring
+}
+
+// SealedMessage is a message received by a Sealed broker
+type SealedMessage struct {
+	Message
+	s *Sealed
+}
+
+// Body returns the body of the message, or nil if it could not be opened. Use Decode to find out
+// why.
+func (m *SealedMessage) Body() (data interface{}) {
+	if m.Decode(&data) != nil {
+		return nil
+	}
+	return
+}
+
+// Decode verifies the message and decodes its body into v. It returns a *SealError if the message
+// was tampered with.
+func (m *SealedMessage) Decode(v interface{}) error {
+	switch b := m.Message.Body().(type) {
+	case []byte:
+		return m.s.Keys.Open(m.Event(), b, v)
+	case string:
+		return m.s.Keys.Open(m.Event(), []byte(b), v)
+	default:
+		return &SealError{Event: m.Event(), Err: ErrMalformedEnvelope}
+	}
+}
+
+// Reply seals data and replies with it
+func (m *SealedMessage) Reply(ctx context.Context, data interface{}) error {
+	b, err := m.s.Keys.Seal(m.Event(), data)
+	if err != nil {
+		return err
+	}
+
+	return m.Message.Reply(ctx, b)
+}
+
+// ID returns the ID of the wrapped message, if it has one
+func (m *SealedMessage) ID() string {
+	return MessageID(m.Message)
+}
+
+// Nack gives up on the wrapped message, if it is a Nacker
+func (m *SealedMessage) Nack(ctx context.Context) error {
+	return Nack(ctx, m.Message)
+}
+
+// Publish seals data and publishes it
+func (s *Sealed) Publish(ctx context.Context, event string, data interface{}) error {
+	b, err := s.Keys.Seal(event, data)
+	if err != nil {
+		return err
+	}
+
+	return s.Broker.Publish(ctx, event, b)
+}
+
+// Subscribe implements Broker interface
+func (s *Sealed) Subscribe(ctx context.Context, events []string, messages chan<- Message) error {
+	return relay(ctx, s.Broker, events, messages, func(msg Message) Message {
+		return &SealedMessage{Message: msg, s: s}
+	})
+}
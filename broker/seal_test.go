@@ -0,0 +1,106 @@
+package broker
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSealed(t *testing.T) {
+	ctx := context.Background()
+	r, w := io.Pipe()
+	key := Key{ID: "1", Sign: []byte("sign"), Encrypt: bytes.Repeat([]byte{1}, 32)}
+	keys, err := NewKeyring(key)
+	assert.NoError(t, err)
+	s := &Sealed{Broker: &RWBroker{R: r, W: w}, Keys: keys}
+
+	msgs := make(chan Message)
+	go func() {
+		_ = s.Subscribe(ctx, []string{"foo"}, msgs)
+	}()
+
+	go func() {
+		assert.NoError(t, s.Publish(ctx, "foo", map[string]interface{}{"token": "secret"}))
+	}()
+
+	msg := <-msgs
+	var body struct {
+		Token string `codec:"token"`
+	}
+	assert.NoError(t, msg.(*SealedMessage).Decode(&body))
+	assert.Equal(t, "secret", body.Token)
+
+	raw := msg.(*SealedMessage).Message.Body().([]byte)
+	assert.False(t, bytes.Contains(raw, []byte("secret")))
+}
+
+func TestKeyringOpen(t *testing.T) {
+	old := Key{ID: "old", Sign: []byte("old")}
+	current := Key{ID: "new", Sign: []byte("new"), Encrypt: bytes.Repeat([]byte{2}, 16)}
+	keys, err := NewKeyring(old)
+	assert.NoError(t, err)
+	assert.NoError(t, keys.Add(current))
+
+	sealedOld, err := keys.Seal("foo", "bar")
+	assert.NoError(t, err)
+
+	assert.NoError(t, keys.Use("new"))
+	sealedNew, err := keys.Seal("foo", "bar")
+	assert.NoError(t, err)
+
+	var body string
+	assert.NoError(t, keys.Open("foo", sealedOld, &body))
+	assert.Equal(t, "bar", body)
+	assert.NoError(t, keys.Open("foo", sealedNew, &body))
+	assert.Equal(t, "bar", body)
+
+	var sealErr *SealError
+	err = keys.Open("INTERACTION_CREATE", sealedOld, &body)
+	assert.True(t, errors.As(err, &sealErr))
+	assert.ErrorIs(t, err, ErrBadSignature)
+	assert.Equal(t, "old", sealErr.KeyID)
+
+	tampered := append([]byte(nil), sealedNew...)
+	tampered[len(tampered)-40] ^= 1
+	assert.ErrorIs(t, keys.Open("foo", tampered, &body), ErrBadSignature)
+
+	assert.ErrorIs(t, keys.Open("foo", []byte("garbage"), &body), ErrMalformedEnvelope)
+
+	keys.Remove("old")
+	assert.ErrorIs(t, keys.Open("foo", sealedOld, &body), ErrUnknownKey)
+	assert.ErrorIs(t, keys.Use("old"), ErrUnknownKey)
+}
+
+func TestKeyringMaxAge(t *testing.T) {
+	keys, err := NewKeyring(Key{ID: "1", Sign: []byte("sign")})
+	assert.NoError(t, err)
+
+	sealed, err := keys.Seal("foo", "bar")
+	assert.NoError(t, err)
+
+	var body string
+	keys.MaxAge = time.Millisecond
+	time.Sleep(2 * time.Millisecond)
+	assert.ErrorIs(t, keys.Open("foo", sealed, &body), ErrExpired)
+
+	keys.MaxAge = -1
+	assert.NoError(t, keys.Open("foo", sealed, &body))
+	assert.Equal(t, "bar", body)
+}
+
+func TestInvalidKey(t *testing.T) {
+	_, err := NewKeyring(Key{ID: "1"})
+	assert.ErrorIs(t, err, ErrInvalidKey)
+
+	_, err = NewKeyring(Key{ID: "1", Sign: []byte("sign")}, Key{ID: "2", Sign: []byte("sign"), Encrypt: []byte("short")})
+	assert.ErrorIs(t, err, ErrInvalidKey)
+
+	keys, err := NewKeyring(Key{ID: "1", Sign: []byte("sign")})
+	assert.NoError(t, err)
+	assert.ErrorIs(t, keys.Add(Key{Sign: []byte("sign")}), ErrInvalidKey)
+}
@@ -0,0 +1,253 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// EnvelopeVersion is the version of the envelope that Versioned wraps payloads in
+const EnvelopeVersion = 1
+
+var (
+	// ErrUnknownVersion occurs when a payload has a schema version that cannot be converted to the
+	// registered one
+	ErrUnknownVersion = errors.New("unknown schema version")
+
+	// ErrUnknownEnvelope occurs when a message has an envelope newer than EnvelopeVersion
+	ErrUnknownEnvelope = errors.New("unknown envelope version")
+)
+
+// Upcaster converts a payload from one schema version of an event to the next. It decodes the old
+// payload with decode and returns the new one.
+type Upcaster func(decode func(v interface{}) error) (interface{}, error)
+
+type schema struct {
+	version   int
+	newValue  func() interface{}
+	upcasters map[int]Upcaster
+}
+
+// Registry holds the schema version of each event, the type its payloads decode into, and the
+// upcasters that convert payloads of older versions
+type Registry struct {
+	mux     sync.RWMutex
+	schemas map[string]*schema
+}
+
+// NewRegistry makes an empty registry
+func NewRegistry() *Registry {
+	return &Registry{schemas: make(map[string]*schema)}
+}
+
+func (r *Registry) schema(event string) *schema {
+	s, ok := r.schemas[event]
+	if !ok {
+		s = &schema{upcasters: make(map[int]Upcaster)}
+		r.schemas[event] = s
+	}
+	return s
+}
+
+// Register declares the current schema version of an event. Payloads are decoded into the value
+// newValue returns, which should be a pointer.
+func (r *Registry) Register(event string, version int, newValue func() interface{}) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	s := r.schema(event)
+	s.version = version
+	s.newValue = newValue
+}
+
+// Upcast registers the conversion of payloads of an event from version from to from+1. Payloads
+// of any version with a chain of upcasters to the registered one are accepted.
+func (r *Registry) Upcast(event string, from int, upcast Upcaster) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	r.schema(event).upcasters[from] = upcast
+}
+
+// Version returns the registered schema version of an event, or zero if it is not registered
+func (r *Registry) Version(event string) int {
+	r.mux.RLock()
+	defer r.mux.RUnlock()
+
+	if s, ok := r.schemas[event]; ok {
+		return s.version
+	}
+	return 0
+}
+
+// Decode decodes a payload of the given schema version into the registered type of the event,
+// upcasting it if it is older. Payloads of events that are not registered are decoded as they are.
+func (r *Registry) Decode(event string, version int, data []byte) (v interface{}, err error) {
+	r.mux.RLock()
+	s, ok := r.schemas[event]
+	r.mux.RUnlock()
+
+	if !ok || s.newValue == nil {
+		err = Decode(data, &v)
+		return
+	}
+
+	for ; version < s.version; version++ {
+		upcast, ok := s.upcasters[version]
+		if !ok {
+			return nil, ErrUnknownVersion
+		}
+
+		current := data
+		if v, err = upcast(func(v interface{}) error { return Decode(current, v) }); err != nil {
+			return
+		}
+		if data, err = Encode(v); err != nil {
+			return
+		}
+	}
+
+	if version != s.version {
+		return nil, ErrUnknownVersion
+	}
+
+	v = s.newValue()
+	err = Decode(data, v)
+	return
+}
+
+// versionedEnvelope carries a payload with the versions it was published with. The payload is
+// encoded on its own so that it can be decoded straight into its registered type. The envelope
+// version is stored under a key that unversioned payloads are not expected to have, since only
+// bodies with it are opened as envelopes.
+type versionedEnvelope struct {
+	V int    `codec:"$spectacles_envelope"`
+	S int    `codec:"s"`
+	D []byte `codec:"d"`
+}
+
+// DeadLetter is published in place of a message that Versioned could not decode
+type DeadLetter struct {
+	Event         string `codec:"event"`
+	ID            string `codec:"id,omitempty"`
+	Envelope      int    `codec:"envelope"`
+	SchemaVersion int    `codec:"schema_version"`
+	Reason        string `codec:"reason"`
+
+	// Data is the decoded body of the message encoded again, or empty if the body could not be
+	// decoded, in which case Reason says why
+	Data []byte `codec:"data,omitempty"`
+}
+
+// Versioned wraps a broker to publish payloads in envelopes that carry their schema version, and
+// to decode received payloads into the types registered for their events. Payloads published
+// without an envelope have schema version zero.
+//
+// Messages whose version cannot be decoded are published as a DeadLetter to the DeadLetter event,
+// which must be set, and acknowledged instead of being delivered. If publishing the dead letter
+// fails, the message is nacked.
+type Versioned struct {
+	Broker
+	Registry   *Registry
+	DeadLetter string
+}
+
+// VersionedMessage is a message received by a Versioned broker
+type VersionedMessage struct {
+	Message
+	value         interface{}
+	schemaVersion int
+}
+
+// Body returns the payload decoded into the type registered for the event
+func (m *VersionedMessage) Body() interface{} {
+	return m.value
+}
+
+// SchemaVersion returns the schema version the payload was published with, before upcasting
+func (m *VersionedMessage) SchemaVersion() int {
+	return m.schemaVersion
+}
+
+// ID returns the ID of the wrapped message, if it has one
+func (m *VersionedMessage) ID() string {
+	return MessageID(m.Message)
+}
+
+// Nack gives up on the wrapped message, if it is a Nacker
+func (m *VersionedMessage) Nack(ctx context.Context) error {
+	return Nack(ctx, m.Message)
+}
+
+// Publish wraps data in an envelope with the registered schema version of the event
+func (v *Versioned) Publish(ctx context.Context, event string, data interface{}) error {
+	b, err := Encode(data)
+	if err != nil {
+		return err
+	}
+
+	return v.Broker.Publish(ctx, event, versionedEnvelope{
+		V: EnvelopeVersion,
+		S: v.Registry.Version(event),
+		D: b,
+	})
+}
+
+// Subscribe implements Broker interface
+func (v *Versioned) Subscribe(ctx context.Context, events []string, messages chan<- Message) error {
+	return relay(ctx, v.Broker, events, messages, func(msg Message) Message {
+		var (
+			env versionedEnvelope
+			raw []byte
+		)
+		body, err := DecodeBody(msg)
+		if err == nil {
+			env, raw, err = openEnvelope(body)
+		}
+		if err == nil && env.V > EnvelopeVersion {
+			err = ErrUnknownEnvelope
+		}
+
+		var value interface{}
+		if err == nil {
+			value, err = v.Registry.Decode(msg.Event(), env.S, env.D)
+		}
+
+		if err != nil {
+			v.deadLetter(ctx, msg, env, raw, err)
+			return nil
+		}
+
+		return &VersionedMessage{Message: msg, value: value, schemaVersion: env.S}
+	})
+}
+
+func (v *Versioned) deadLetter(ctx context.Context, msg Message, env versionedEnvelope, raw []byte, reason error) {
+	err := v.Broker.Publish(ctx, v.DeadLetter, DeadLetter{
+		Event:         msg.Event(),
+		ID:            MessageID(msg),
+		Envelope:      env.V,
+		SchemaVersion: env.S,
+		Reason:        reason.Error(),
+		Data:          raw,
+	})
+	if err != nil {
+		_ = Nack(ctx, msg)
+		return
+	}
+
+	_ = msg.Ack(ctx)
+}
+
+// openEnvelope reads the envelope of a decoded body, returning it along with the body encoded again.
+// Bodies without one are payloads of schema version zero.
+func openEnvelope(body interface{}) (env versionedEnvelope, raw []byte, err error) {
+	if raw, err = Encode(body); err != nil {
+		return
+	}
+
+	if Decode(raw, &env) != nil || env.V <= 0 {
+		env = versionedEnvelope{D: raw}
+	}
+	return
+}
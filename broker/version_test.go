@@ -0,0 +1,154 @@
+package broker
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type messageV1 struct {
+	Text string `codec:"text"`
+}
+
+type messageV2 struct {
+	Content string `codec:"content"`
+}
+
+type deadLetterBroker struct {
+	bridgeSource
+	published map[string][]interface{}
+}
+
+func (b *deadLetterBroker) Publish(ctx context.Context, event string, data interface{}) error {
+	b.published[event] = append(b.published[event], data)
+	return nil
+}
+
+func TestVersioned(t *testing.T) {
+	ctx := context.Background()
+
+	old := NewRegistry()
+	old.Register("MESSAGE_CREATE", 1, func() interface{} { return new(messageV1) })
+
+	registry := NewRegistry()
+	registry.Register("MESSAGE_CREATE", 2, func() interface{} { return new(messageV2) })
+	registry.Upcast("MESSAGE_CREATE", 1, func(decode func(interface{}) error) (interface{}, error) {
+		var m messageV1
+		err := decode(&m)
+		return messageV2{Content: m.Text}, err
+	})
+
+	// capture what publishers with each registry send
+	published := &deadLetterBroker{published: make(map[string][]interface{})}
+	assert.NoError(t, (&Versioned{Broker: published, Registry: old}).Publish(ctx, "MESSAGE_CREATE", messageV1{"old"}))
+	assert.NoError(t, (&Versioned{Broker: published, Registry: registry}).Publish(ctx, "MESSAGE_CREATE", messageV2{"new"}))
+	newer := NewRegistry()
+	newer.Register("MESSAGE_CREATE", 3, nil)
+	assert.NoError(t, (&Versioned{Broker: published, Registry: newer}).Publish(ctx, "MESSAGE_CREATE", "newer"))
+
+	var source bridgeSource
+	for _, data := range published.published["MESSAGE_CREATE"] {
+		source = append(source, &bridgeMessage{testMessage: testMessage{IOPacket: IOPacket{E: "MESSAGE_CREATE", D: data}}})
+	}
+	unversioned := &bridgeMessage{testMessage: testMessage{IOPacket: IOPacket{E: "MESSAGE_CREATE", D: "raw"}}}
+	source = append(source, unversioned)
+
+	b := &deadLetterBroker{bridgeSource: source, published: make(map[string][]interface{})}
+	v := &Versioned{Broker: b, Registry: registry, DeadLetter: "DEAD_LETTER"}
+
+	msgs := make(chan Message, len(source))
+	assert.NoError(t, v.Subscribe(ctx, []string{"MESSAGE_CREATE"}, msgs))
+	assert.Len(t, msgs, 2)
+
+	msg := (<-msgs).(*VersionedMessage)
+	assert.Equal(t, &messageV2{Content: "old"}, msg.Body())
+	assert.Equal(t, 1, msg.SchemaVersion())
+
+	msg = (<-msgs).(*VersionedMessage)
+	assert.Equal(t, &messageV2{Content: "new"}, msg.Body())
+	assert.Equal(t, 2, msg.SchemaVersion())
+
+	dead := b.published["DEAD_LETTER"]
+	if assert.Len(t, dead, 2) {
+		assert.Equal(t, 3, dead[0].(DeadLetter).SchemaVersion)
+		assert.Equal(t, ErrUnknownVersion.Error(), dead[0].(DeadLetter).Reason)
+		assert.Equal(t, 0, dead[1].(DeadLetter).SchemaVersion)
+
+		raw, err := Encode("raw")
+		assert.NoError(t, err)
+		assert.Equal(t, raw, dead[1].(DeadLetter).Data)
+	}
+	assert.True(t, source[2].acked)
+	assert.True(t, unversioned.acked)
+}
+
+func TestVersionedUnversioned(t *testing.T) {
+	ctx := context.Background()
+
+	// gateway payloads have a "v" key of their own, which must not be mistaken for an envelope
+	ready := map[string]interface{}{"v": 10, "session_id": "abc"}
+	source := bridgeSource{{testMessage: testMessage{IOPacket: IOPacket{E: "READY", D: ready}}}}
+	b := &deadLetterBroker{bridgeSource: source, published: make(map[string][]interface{})}
+	v := &Versioned{Broker: b, Registry: NewRegistry(), DeadLetter: "DEAD_LETTER"}
+
+	msgs := make(chan Message, 1)
+	assert.NoError(t, v.Subscribe(ctx, []string{"READY"}, msgs))
+	assert.Empty(t, b.published["DEAD_LETTER"])
+
+	if assert.Len(t, msgs, 1) {
+		msg := (<-msgs).(*VersionedMessage)
+		assert.Equal(t, 0, msg.SchemaVersion())
+		assert.EqualValues(t, 10, msg.Body().(map[interface{}]interface{})["v"])
+	}
+}
+
+// undecodableDeadLetters delivers messages that cannot be decoded and records dead letters
+type undecodableDeadLetters struct {
+	undecodableSource
+	dead []interface{}
+}
+
+func (b *undecodableDeadLetters) Publish(ctx context.Context, event string, data interface{}) error {
+	b.dead = append(b.dead, data)
+	return nil
+}
+
+func TestVersionedUndecodable(t *testing.T) {
+	ctx := context.Background()
+	msg := &undecodableMessage{bridgeMessage{testMessage: testMessage{IOPacket: IOPacket{E: "READY"}}}}
+	b := &undecodableDeadLetters{undecodableSource: undecodableSource{msg}}
+	v := &Versioned{Broker: b, Registry: NewRegistry(), DeadLetter: "DEAD_LETTER"}
+
+	msgs := make(chan Message, 1)
+	assert.NoError(t, v.Subscribe(ctx, []string{"READY"}, msgs))
+	assert.Empty(t, msgs)
+	assert.True(t, msg.acked)
+
+	// the reason is recorded instead of an empty body
+	if assert.Len(t, b.dead, 1) {
+		dead := b.dead[0].(DeadLetter)
+		assert.Equal(t, errUndecodable.Error(), dead.Reason)
+		assert.Empty(t, dead.Data)
+	}
+}
+
+func TestVersionedRW(t *testing.T) {
+	ctx := context.Background()
+	r, w := io.Pipe()
+	registry := NewRegistry()
+	registry.Register("MESSAGE_CREATE", 2, func() interface{} { return new(messageV2) })
+	v := &Versioned{Broker: &RWBroker{R: r, W: w}, Registry: registry, DeadLetter: "DEAD_LETTER"}
+
+	msgs := make(chan Message)
+	go func() {
+		_ = v.Subscribe(ctx, []string{"MESSAGE_CREATE"}, msgs)
+	}()
+
+	go func() {
+		assert.NoError(t, v.Publish(ctx, "MESSAGE_CREATE", messageV2{"hello"}))
+	}()
+
+	assert.Equal(t, &messageV2{Content: "hello"}, (<-msgs).Body())
+}
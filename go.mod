@@ -4,7 +4,8 @@ require (
 	github.com/bwmarrin/snowflake v0.0.0-20180412010544-68117e6bbede
 	github.com/google/uuid v1.2.0
 	github.com/joho/godotenv v1.3.0
-	github.com/mediocregopher/radix/v4 v4.0.0
+	github.com/mediocregopher/radix/v4 v4.1.4
+	github.com/prometheus/client_golang v1.11.1
 	github.com/rabbitmq/amqp091-go v1.2.0
 	github.com/stretchr/testify v1.7.0
 	github.com/ugorji/go/codec v1.2.6
@@ -0,0 +1,95 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/bwmarrin/snowflake"
+	"github.com/spec-tacles/go/types"
+)
+
+// AuditLogFilters select the entries of an audit log to get. Zero fields do not filter.
+type AuditLogFilters struct {
+	UserID snowflake.ID
+
+	// ActionType is one of the types.AuditLog* events
+	ActionType int
+
+	// Before is the ID of the oldest entry of the previous page
+	Before snowflake.ID
+
+	// Limit is the number of entries to get, from 1 to 100. Discord defaults to 50.
+	Limit int
+}
+
+func (f AuditLogFilters) query() url.Values {
+	query := url.Values{}
+	if f.UserID != 0 {
+		query.Set("user_id", f.UserID.String())
+	}
+	if f.ActionType != 0 {
+		query.Set("action_type", strconv.Itoa(f.ActionType))
+	}
+	if f.Before != 0 {
+		query.Set("before", f.Before.String())
+	}
+	if f.Limit > 0 {
+		query.Set("limit", strconv.Itoa(f.Limit))
+	}
+	return query
+}
+
+// GetAuditLog gets entries of the audit log of a guild, newest first. Use an AuditLogPager to get
+// every page.
+func (c *Client) GetAuditLog(ctx context.Context, guildID snowflake.ID, filters AuditLogFilters, opts ...RequestOption) (*types.AuditLog, error) {
+	log := &types.AuditLog{}
+	if err := c.request(ctx, http.MethodGet, withQuery(guildPath(guildID)+"/audit-logs", filters.query()), nil, log, opts...); err != nil {
+		return nil, err
+	}
+	return log, nil
+}
+
+// AuditLogPager gets the pages of the audit log of a guild, newest first
+type AuditLogPager struct {
+	Client  *Client
+	GuildID snowflake.ID
+	Filters AuditLogFilters
+
+	done bool
+}
+
+// NewAuditLogPager makes a pager that starts at Filters.Before, or at the newest entry if it is
+// zero
+func NewAuditLogPager(client *Client, guildID snowflake.ID, filters AuditLogFilters) *AuditLogPager {
+	return &AuditLogPager{Client: client, GuildID: guildID, Filters: filters}
+}
+
+// Next gets the next page of the audit log, or nil once every page was read
+func (p *AuditLogPager) Next(ctx context.Context, opts ...RequestOption) (*types.AuditLog, error) {
+	if p.done {
+		return nil, nil
+	}
+
+	log, err := p.Client.GetAuditLog(ctx, p.GuildID, p.Filters, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := p.Filters.Limit
+	if limit <= 0 {
+		limit = 50
+	}
+
+	entries := log.AuditLogEntries
+	if len(entries) < limit {
+		p.done = true
+	}
+	if len(entries) == 0 {
+		return nil, nil
+	}
+
+	p.Filters.Before = entries[len(entries)-1].ID
+	return log, nil
+}
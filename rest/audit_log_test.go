@@ -0,0 +1,82 @@
+package rest_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/spec-tacles/go/rest"
+	"github.com/spec-tacles/go/types"
+)
+
+func TestWithReason(t *testing.T) {
+	var reason string
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		reason = r.Header.Get("X-Audit-Log-Reason")
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	if err := client.CreateBan(context.Background(), 1, 2, 0, rest.WithReason("spam & ads: 100%")); err != nil {
+		t.Fatal(err)
+	}
+	if reason != "spam%20&%20ads:%20100%25" {
+		t.Errorf("unexpected reason %q", reason)
+	}
+}
+
+func TestAuditLogPager(t *testing.T) {
+	var queries []string
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.RawQuery)
+
+		// entries 10 to 1, newest first
+		before := 11
+		if b := r.URL.Query().Get("before"); b != "" {
+			before, _ = strconv.Atoi(b)
+		}
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+		var entries []string
+		for id := before - 1; id > 0 && len(entries) < limit; id-- {
+			entries = append(entries, fmt.Sprintf(`{"id": "%d", "user_id": "2", "action_type": 22}`, id))
+		}
+		fmt.Fprintf(w, `{"audit_log_entries": [%s]}`, strings.Join(entries, ","))
+	})
+
+	pager := rest.NewAuditLogPager(client, 1, rest.AuditLogFilters{
+		UserID:     2,
+		ActionType: types.AuditLogMemberBanAdd,
+		Limit:      4,
+	})
+
+	var ids []string
+	for {
+		log, err := pager.Next(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if log == nil {
+			break
+		}
+
+		for _, entry := range log.AuditLogEntries {
+			ids = append(ids, entry.ID.String())
+		}
+	}
+
+	if strings.Join(ids, ",") != "10,9,8,7,6,5,4,3,2,1" {
+		t.Errorf("unexpected entries %v", ids)
+	}
+
+	expected := []string{
+		"action_type=22&limit=4&user_id=2",
+		"action_type=22&before=7&limit=4&user_id=2",
+		"action_type=22&before=3&limit=4&user_id=2",
+	}
+	if strings.Join(queries, " ") != strings.Join(expected, " ") {
+		t.Errorf("unexpected queries %v", queries)
+	}
+}
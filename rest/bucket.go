@@ -1,20 +1,30 @@
 package rest
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"io/ioutil"
 	"net/http"
 	"strconv"
-	"sync"
 	"time"
 )
 
 // Bucket represents a ratelimit bucket
 type Bucket struct {
-	mux *sync.Mutex
+	// lock is held by the request in progress. It is a channel so that waiting for it can be
+	// cancelled.
+	lock chan struct{}
 
 	Client *Client
-	Route  string
+
+	// Route is the method and shared route of the requests that created this bucket, and Major is
+	// the major parameter they have in common
+	Route string
+	Major string
+
+	// Hash is the bucket hash Discord assigned to Route, once it is known
+	Hash string
 
 	Remaining int64
 	Reset     time.Time
@@ -22,15 +32,15 @@ type Bucket struct {
 }
 
 type ratelimited struct {
-	Message    string `json:"message"`
-	RetryAfter int    `json:"retry_after"`
-	Global     bool   `json:"global"`
+	Message    string  `json:"message"`
+	RetryAfter float64 `json:"retry_after"`
+	Global     bool    `json:"global"`
 }
 
 // NewBucket makes a new bucket
 func NewBucket(client *Client, route string) *Bucket {
 	return &Bucket{
-		mux:       &sync.Mutex{},
+		lock:      make(chan struct{}, 1),
 		Client:    client,
 		Route:     route,
 		Remaining: 1,
@@ -39,27 +49,80 @@ func NewBucket(client *Client, route string) *Bucket {
 	}
 }
 
-// Do a request in this bucket
+// Do a request in this bucket, retrying it according to the retry policy of the client. Waiting for
+// the bucket, its ratelimits and retries stops once the context of the request is done. Like
+// http.Client, the body of the request is closed even on errors.
 func (b *Bucket) Do(req *http.Request) (res *http.Response, err error) {
-	b.mux.Lock()
-	defer b.mux.Unlock()
+	defer func() {
+		if err != nil && req.Body != nil {
+			req.Body.Close()
+		}
+	}()
+
+	retry := b.Client.Retry
+	if retry.allows(req) {
+		if err = bufferBody(req); err != nil {
+			return
+		}
+	}
+
+	for attempt := 1; ; attempt++ {
+		res, err = b.do(req)
+		if !retry.shouldRetry(req, res, err, attempt) {
+			return
+		}
+
+		if res != nil {
+			res.Body.Close()
+		}
+
+		if err = sleep(req.Context(), retry.backoff(attempt)); err != nil {
+			return nil, err
+		}
+
+		if req, err = rewind(req); err != nil {
+			return
+		}
+	}
+}
+
+// do sends a request once
+func (b *Bucket) do(req *http.Request) (res *http.Response, err error) {
+	ctx := req.Context()
+	select {
+	case b.lock <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	defer func() { <-b.lock }()
 
 	if b.Client.GloballyLimited() {
-		wait := time.Until(b.Client.GlobalReset)
-		<-time.After(wait)
+		if err = sleep(ctx, time.Until(b.Client.GlobalReset)); err != nil {
+			return
+		}
 	}
 
 	if b.Remaining <= 0 {
-		wait := time.Until(b.Reset)
-		<-time.After(wait)
+		if err = sleep(ctx, time.Until(b.Reset)); err != nil {
+			return
+		}
 		b.Remaining = b.Limit
 	}
 
+	if err = b.Client.wait(ctx); err != nil {
+		return
+	}
+
 	res, err = b.Client.HTTP.Do(req)
 	if err != nil {
 		return
 	}
 
+	// shared ratelimits are not caused by this client, so they don't count as invalid requests
+	if b.Client.InvalidRequests != nil && res.Header.Get("x-ratelimit-scope") != "shared" {
+		b.Client.InvalidRequests.Record(res.StatusCode)
+	}
+
 	limit := res.Header.Get("x-ratelimit-limit")
 	if limit != "" {
 		b.Limit, err = strconv.ParseInt(limit, 10, 32)
@@ -68,6 +131,8 @@ func (b *Bucket) Do(req *http.Request) (res *http.Response, err error) {
 		}
 	}
 
+	b.Client.learnHash(b, res.Header.Get("x-ratelimit-bucket"))
+
 	remaining := res.Header.Get("x-ratelimit-remaining")
 	if remaining != "" {
 		b.Remaining, err = strconv.ParseInt(remaining, 10, 32)
@@ -77,67 +142,95 @@ func (b *Bucket) Do(req *http.Request) (res *http.Response, err error) {
 		return
 	}
 
-	switch {
-	case res.StatusCode == http.StatusTooManyRequests:
+	if res.StatusCode == http.StatusTooManyRequests {
 		err = b.handle429(res)
-	case res.StatusCode >= 500 && res.StatusCode < 600:
-		return b.handle500(req) // retry on 500
-	default:
+	} else {
 		err = b.handleNormal(res)
 	}
 	return
 }
 
-func (b *Bucket) handleNormal(res *http.Response) (err error) {
-	// handle normal bucket resets
+// handleNormal updates the reset of the bucket from the headers of a response
+func (b *Bucket) handleNormal(res *http.Response) error {
+	if resetAfter, ok := parseSeconds(res.Header.Get("x-ratelimit-reset-after")); ok {
+		b.Reset = time.Now().Add(resetAfter)
+		return nil
+	}
+
 	reset := res.Header.Get("x-ratelimit-reset")
 	if reset == "" {
 		return nil
 	}
 
-	var resetTime float64
-	resetTime, err = strconv.ParseFloat(reset, 64)
+	resetTime, err := strconv.ParseFloat(reset, 64)
 	if err != nil {
-		return
+		return err
 	}
 
-	sent, err := time.Parse(res.Header.Get("date"), time.RFC1123)
+	// the reset is a timestamp of the server, so correct it by the difference between the clocks
+	sent, err := http.ParseTime(res.Header.Get("date"))
 	if err != nil {
 		sent = time.Now()
 	}
 
-	// calculate clock difference when determining reset time based on header timestamp
 	diff := time.Since(sent)
-	b.Reset = time.Unix(int64(resetTime), 0).Add(diff)
+	b.Reset = time.Unix(0, int64(resetTime*float64(time.Second))).Add(diff)
 	return nil
 }
 
-func (b *Bucket) handle429(res *http.Response) (err error) {
-	var (
-		body  = ratelimited{}
-		bytes []byte
-	)
-
-	bytes, err = ioutil.ReadAll(res.Body)
+// handle429 updates the bucket or the global reset from a ratelimited response. The body is kept
+// readable for the caller.
+func (b *Bucket) handle429(res *http.Response) error {
+	data, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
 	if err != nil {
-		return
+		return err
 	}
+	res.Body = ioutil.NopCloser(bytes.NewReader(data))
 
-	res.Body.Close()
-	json.Unmarshal(bytes, &body)
+	body := ratelimited{}
+	json.Unmarshal(data, &body)
+
+	retryAfter := time.Duration(body.RetryAfter * float64(time.Second))
+	if d, ok := parseSeconds(res.Header.Get("x-ratelimit-reset-after")); ok {
+		retryAfter = d
+	}
+	if d, ok := parseSeconds(res.Header.Get("retry-after")); ok && d > retryAfter {
+		retryAfter = d
+	}
 
-	// handle 429 resets
-	reset := time.Now().Add(time.Duration(body.RetryAfter))
-	if body.Global {
+	reset := time.Now().Add(retryAfter)
+	global := body.Global ||
+		res.Header.Get("x-ratelimit-global") == "true" ||
+		res.Header.Get("x-ratelimit-scope") == "global"
+
+	if global {
 		b.Client.GlobalReset = reset
 	} else {
+		b.Remaining = 0
 		b.Reset = reset
 	}
+	return nil
+}
 
-	return
+// parseSeconds parses a header holding a number of seconds, and whether it held one
+func parseSeconds(header string) (time.Duration, bool) {
+	seconds, err := strconv.ParseFloat(header, 64)
+	if err != nil {
+		return 0, false
+	}
+	return time.Duration(seconds * float64(time.Second)), true
 }
 
-func (b *Bucket) handle500(req *http.Request) (*http.Response, error) {
-	<-time.After(5 * time.Second)
-	return b.Do(req)
+// sleep waits for the duration to pass, or returns the error of the context if it is done first
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
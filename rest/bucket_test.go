@@ -0,0 +1,104 @@
+package rest_test
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/spec-tacles/go/rest"
+)
+
+// timeRequests makes two requests to the path and returns how long the second one waited
+func timeRequests(t *testing.T, client *rest.Client, path string) time.Duration {
+	t.Helper()
+	client.DoJSON(http.MethodGet, path, nil, nil)
+
+	start := time.Now()
+	client.DoJSON(http.MethodGet, path, nil, nil)
+	return time.Since(start)
+}
+
+func assertWaited(t *testing.T, waited, min, max time.Duration) {
+	t.Helper()
+	if waited < min || waited > max {
+		t.Errorf("expected to wait between %s and %s, waited %s", min, max, waited)
+	}
+}
+
+func TestResetAfter(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Limit", "1")
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+		w.Header().Set("X-RateLimit-Reset-After", "0.2")
+		w.Write([]byte("{}"))
+	})
+
+	assertWaited(t, timeRequests(t, client, "/users/@me"), 150*time.Millisecond, time.Second)
+}
+
+func TestResetClockSkew(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		// the clock of the server is an hour behind
+		now := time.Now().Add(-time.Hour)
+		reset := float64(now.Add(300*time.Millisecond).UnixNano()) / float64(time.Second)
+
+		w.Header().Set("Date", now.UTC().Format(http.TimeFormat))
+		w.Header().Set("X-RateLimit-Limit", "1")
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatFloat(reset, 'f', 3, 64))
+		w.Write([]byte("{}"))
+	})
+
+	// the date header only has whole seconds, which can add up to a second to the wait
+	assertWaited(t, timeRequests(t, client, "/users/@me"), 250*time.Millisecond, 2*time.Second)
+}
+
+func TestRetryAfter(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Scope", "user")
+		w.Header().Set("Retry-After", "0.3")
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"message": "You are being rate limited.", "retry_after": 0.1, "global": false}`))
+	})
+
+	err := client.DoJSON(http.MethodGet, "/users/@me", nil, nil)
+
+	var apiErr *rest.APIError
+	if !errors.As(err, &apiErr) || apiErr.Message != "You are being rate limited." {
+		t.Errorf("expected a ratelimit error, got %v", err)
+	}
+	if client.GloballyLimited() {
+		t.Error("user ratelimit was treated as global")
+	}
+
+	assertWaited(t, timeRequests(t, client, "/users/@me"), 250*time.Millisecond, time.Second)
+}
+
+func TestGlobalRatelimit(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v8/users/@me" {
+			w.Header().Set("X-RateLimit-Global", "true")
+			w.Header().Set("X-RateLimit-Scope", "global")
+			w.Header().Set("Retry-After", "0.2")
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"message": "You are being rate limited.", "retry_after": 0.2, "global": true}`))
+			return
+		}
+		w.Write([]byte("{}"))
+	})
+
+	client.DoJSON(http.MethodGet, "/users/@me", nil, nil)
+	if !client.GloballyLimited() {
+		t.Fatal("expected to be globally ratelimited")
+	}
+
+	// the global ratelimit applies to other routes too
+	start := time.Now()
+	if err := client.DoJSON(http.MethodGet, "/gateway/bot", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	assertWaited(t, time.Since(start), 150*time.Millisecond, time.Second)
+}
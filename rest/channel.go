@@ -0,0 +1,195 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/bwmarrin/snowflake"
+	"github.com/spec-tacles/go/types"
+)
+
+// ModifyChannelParams are the fields of a channel to change. Nil fields are left as they are.
+type ModifyChannelParams struct {
+	Name                 string            `json:"name,omitempty"`
+	Position             *int              `json:"position,omitempty"`
+	Topic                *string           `json:"topic,omitempty"`
+	NSFW                 *bool             `json:"nsfw,omitempty"`
+	RateLimitPerUser     *int              `json:"rate_limit_per_user,omitempty"`
+	Bitrate              *int              `json:"bitrate,omitempty"`
+	UserLimit            *int              `json:"user_limit,omitempty"`
+	PermissionOverwrites []types.Overwrite `json:"permission_overwrites,omitempty"`
+	ParentID             *snowflake.ID     `json:"parent_id,omitempty"`
+}
+
+// GetMessagesParams select the messages of a channel to get. Only one of Before, After and Around
+// can be set.
+type GetMessagesParams struct {
+	Before snowflake.ID
+	After  snowflake.ID
+	Around snowflake.ID
+
+	// Limit is the number of messages to get, from 1 to 100. Discord defaults to 50.
+	Limit int
+}
+
+// CreateMessageParams are the parameters of a new message
+type CreateMessageParams struct {
+	Content string        `json:"content,omitempty"`
+	Nonce   string        `json:"nonce,omitempty"`
+	TTS     bool          `json:"tts,omitempty"`
+	Embeds  []types.Embed `json:"embeds,omitempty"`
+
+	// Files are uploaded as attachments of the message
+	Files []File `json:"-"`
+}
+
+// EditMessageParams are the fields of a message to change. Nil fields are left as they are.
+type EditMessageParams struct {
+	Content *string        `json:"content,omitempty"`
+	Embeds  *[]types.Embed `json:"embeds,omitempty"`
+	Flags   *int           `json:"flags,omitempty"`
+
+	// Files are uploaded as new attachments of the message
+	Files []File `json:"-"`
+}
+
+func channelPath(channelID snowflake.ID) string {
+	return "/channels/" + channelID.String()
+}
+
+func messagePath(channelID, messageID snowflake.ID) string {
+	return channelPath(channelID) + "/messages/" + messageID.String()
+}
+
+// GetChannel gets a channel
+func (c *Client) GetChannel(ctx context.Context, channelID snowflake.ID, opts ...RequestOption) (*types.Channel, error) {
+	channel := &types.Channel{}
+	if err := c.request(ctx, http.MethodGet, channelPath(channelID), nil, channel, opts...); err != nil {
+		return nil, err
+	}
+	return channel, nil
+}
+
+// ModifyChannel changes the settings of a channel and returns the updated channel
+func (c *Client) ModifyChannel(ctx context.Context, channelID snowflake.ID, params ModifyChannelParams, opts ...RequestOption) (*types.Channel, error) {
+	channel := &types.Channel{}
+	if err := c.request(ctx, http.MethodPatch, channelPath(channelID), params, channel, opts...); err != nil {
+		return nil, err
+	}
+	return channel, nil
+}
+
+// DeleteChannel deletes a channel, or closes a DM, and returns it
+func (c *Client) DeleteChannel(ctx context.Context, channelID snowflake.ID, opts ...RequestOption) (*types.Channel, error) {
+	channel := &types.Channel{}
+	if err := c.request(ctx, http.MethodDelete, channelPath(channelID), nil, channel, opts...); err != nil {
+		return nil, err
+	}
+	return channel, nil
+}
+
+// GetMessages gets messages of a channel, newest first
+func (c *Client) GetMessages(ctx context.Context, channelID snowflake.ID, params GetMessagesParams, opts ...RequestOption) ([]types.Message, error) {
+	query := url.Values{}
+	switch {
+	case params.Before != 0:
+		query.Set("before", params.Before.String())
+	case params.After != 0:
+		query.Set("after", params.After.String())
+	case params.Around != 0:
+		query.Set("around", params.Around.String())
+	}
+	if params.Limit > 0 {
+		query.Set("limit", strconv.Itoa(params.Limit))
+	}
+
+	var messages []types.Message
+	if err := c.request(ctx, http.MethodGet, withQuery(channelPath(channelID)+"/messages", query), nil, &messages, opts...); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// GetMessage gets a message of a channel
+func (c *Client) GetMessage(ctx context.Context, channelID, messageID snowflake.ID, opts ...RequestOption) (*types.Message, error) {
+	msg := &types.Message{}
+	if err := c.request(ctx, http.MethodGet, messagePath(channelID, messageID), nil, msg, opts...); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// CreateMessage sends a message to a channel
+func (c *Client) CreateMessage(ctx context.Context, channelID snowflake.ID, params CreateMessageParams, opts ...RequestOption) (*types.Message, error) {
+	msg := &types.Message{}
+	if err := c.requestFiles(ctx, http.MethodPost, channelPath(channelID)+"/messages", params, params.Files, msg, opts...); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// EditMessage edits a message and returns the updated message
+func (c *Client) EditMessage(ctx context.Context, channelID, messageID snowflake.ID, params EditMessageParams, opts ...RequestOption) (*types.Message, error) {
+	msg := &types.Message{}
+	if err := c.requestFiles(ctx, http.MethodPatch, messagePath(channelID, messageID), params, params.Files, msg, opts...); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// DeleteMessage deletes a message
+func (c *Client) DeleteMessage(ctx context.Context, channelID, messageID snowflake.ID, opts ...RequestOption) error {
+	return c.request(ctx, http.MethodDelete, messagePath(channelID, messageID), nil, nil, opts...)
+}
+
+// BulkDeleteMessages deletes up to 100 messages at once. Discord refuses to bulk delete messages
+// older than two weeks.
+func (c *Client) BulkDeleteMessages(ctx context.Context, channelID snowflake.ID, messageIDs []snowflake.ID, opts ...RequestOption) error {
+	switch len(messageIDs) {
+	case 0:
+		return nil
+	case 1:
+		// bulk deletes need at least two messages
+		return c.DeleteMessage(ctx, channelID, messageIDs[0], opts...)
+	}
+
+	body := struct {
+		Messages []snowflake.ID `json:"messages"`
+	}{messageIDs}
+	return c.request(ctx, http.MethodPost, channelPath(channelID)+"/messages/bulk-delete", body, nil, opts...)
+}
+
+// CrosspostMessage publishes a message of an announcement channel to the channels following it
+func (c *Client) CrosspostMessage(ctx context.Context, channelID, messageID snowflake.ID, opts ...RequestOption) (*types.Message, error) {
+	msg := &types.Message{}
+	if err := c.request(ctx, http.MethodPost, messagePath(channelID, messageID)+"/crosspost", nil, msg, opts...); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// GetPinnedMessages gets the pinned messages of a channel
+func (c *Client) GetPinnedMessages(ctx context.Context, channelID snowflake.ID, opts ...RequestOption) ([]types.Message, error) {
+	var messages []types.Message
+	if err := c.request(ctx, http.MethodGet, channelPath(channelID)+"/pins", nil, &messages, opts...); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// PinMessage pins a message in its channel
+func (c *Client) PinMessage(ctx context.Context, channelID, messageID snowflake.ID, opts ...RequestOption) error {
+	return c.request(ctx, http.MethodPut, channelPath(channelID)+"/pins/"+messageID.String(), nil, nil, opts...)
+}
+
+// UnpinMessage unpins a message in its channel
+func (c *Client) UnpinMessage(ctx context.Context, channelID, messageID snowflake.ID, opts ...RequestOption) error {
+	return c.request(ctx, http.MethodDelete, channelPath(channelID)+"/pins/"+messageID.String(), nil, nil, opts...)
+}
+
+// TriggerTyping shows the bot as typing in a channel for 10 seconds, or until it sends a message
+func (c *Client) TriggerTyping(ctx context.Context, channelID snowflake.ID, opts ...RequestOption) error {
+	return c.request(ctx, http.MethodPost, channelPath(channelID)+"/typing", nil, nil, opts...)
+}
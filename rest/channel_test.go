@@ -0,0 +1,142 @@
+package rest_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/snowflake"
+	"github.com/spec-tacles/go/rest"
+)
+
+// request is a request received by a fake Discord API
+type request struct {
+	Method string
+	URL    string
+	Body   string
+}
+
+// newRecordingClient makes a client whose requests are recorded and answered with body
+func newRecordingClient(t *testing.T, body string) (*rest.Client, *[]request) {
+	var requests []request
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		requests = append(requests, request{
+			Method: r.Method,
+			URL:    strings.TrimPrefix(r.URL.RequestURI(), "/api/v8"),
+			Body:   string(data),
+		})
+
+		if body == "" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Write([]byte(body))
+	})
+	return client, &requests
+}
+
+func assertRequest(t *testing.T, requests []request, expected request) {
+	t.Helper()
+	if len(requests) == 0 {
+		t.Fatalf("expected %+v, got no request", expected)
+	}
+
+	if actual := requests[len(requests)-1]; actual != expected {
+		t.Errorf("expected %+v, got %+v", expected, actual)
+	}
+}
+
+func TestChannel(t *testing.T) {
+	ctx := context.Background()
+	client, requests := newRecordingClient(t, `{"id": "1", "name": "general"}`)
+
+	channel, err := client.GetChannel(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if channel.ID != 1 || channel.Name != "general" {
+		t.Errorf("unexpected channel %+v", channel)
+	}
+	assertRequest(t, *requests, request{http.MethodGet, "/channels/1", ""})
+
+	topic := ""
+	if _, err = client.ModifyChannel(ctx, 1, rest.ModifyChannelParams{Name: "general", Topic: &topic}); err != nil {
+		t.Fatal(err)
+	}
+	assertRequest(t, *requests, request{http.MethodPatch, "/channels/1", `{"name":"general","topic":""}`})
+
+	if _, err = client.DeleteChannel(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	assertRequest(t, *requests, request{http.MethodDelete, "/channels/1", ""})
+}
+
+func TestMessages(t *testing.T) {
+	ctx := context.Background()
+	client, requests := newRecordingClient(t, `[{"id": "3", "content": "hello"}]`)
+
+	messages, err := client.GetMessages(ctx, 1, rest.GetMessagesParams{Before: 4, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 1 || messages[0].Content != "hello" {
+		t.Errorf("unexpected messages %+v", messages)
+	}
+	assertRequest(t, *requests, request{http.MethodGet, "/channels/1/messages?before=4&limit=10", ""})
+
+	if _, err = client.GetPinnedMessages(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	assertRequest(t, *requests, request{http.MethodGet, "/channels/1/pins", ""})
+
+	client, requests = newRecordingClient(t, `{"id": "3", "content": "hello"}`)
+	msg, err := client.CreateMessage(ctx, 1, rest.CreateMessageParams{
+		Content: "hello",
+		TTS:     true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.ID != 3 {
+		t.Errorf("unexpected message %+v", msg)
+	}
+	assertRequest(t, *requests, request{http.MethodPost, "/channels/1/messages", `{"content":"hello","tts":true}`})
+
+	content := "edited"
+	if _, err = client.EditMessage(ctx, 1, 3, rest.EditMessageParams{Content: &content}); err != nil {
+		t.Fatal(err)
+	}
+	assertRequest(t, *requests, request{http.MethodPatch, "/channels/1/messages/3", `{"content":"edited"}`})
+
+	if _, err = client.CrosspostMessage(ctx, 1, 3); err != nil {
+		t.Fatal(err)
+	}
+	assertRequest(t, *requests, request{http.MethodPost, "/channels/1/messages/3/crosspost", ""})
+}
+
+func TestMessageActions(t *testing.T) {
+	ctx := context.Background()
+	client, requests := newRecordingClient(t, "")
+
+	tests := []struct {
+		do       func() error
+		expected request
+	}{
+		{func() error { return client.DeleteMessage(ctx, 1, 3) }, request{http.MethodDelete, "/channels/1/messages/3", ""}},
+		{func() error { return client.BulkDeleteMessages(ctx, 1, []snowflake.ID{3, 4}) }, request{http.MethodPost, "/channels/1/messages/bulk-delete", `{"messages":["3","4"]}`}},
+		{func() error { return client.BulkDeleteMessages(ctx, 1, []snowflake.ID{5}) }, request{http.MethodDelete, "/channels/1/messages/5", ""}},
+		{func() error { return client.PinMessage(ctx, 1, 3) }, request{http.MethodPut, "/channels/1/pins/3", ""}},
+		{func() error { return client.UnpinMessage(ctx, 1, 3) }, request{http.MethodDelete, "/channels/1/pins/3", ""}},
+		{func() error { return client.TriggerTyping(ctx, 1) }, request{http.MethodPost, "/channels/1/typing", ""}},
+	}
+
+	for _, test := range tests {
+		if err := test.do(); err != nil {
+			t.Fatal(err)
+		}
+		assertRequest(t, *requests, test.expected)
+	}
+}
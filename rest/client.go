@@ -1,34 +1,57 @@
 package rest
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"io"
 	"net/http"
+	"net/url"
 	"sync"
 	"time"
 )
 
 // Client represents a REST client
 type Client struct {
-	Token       string
-	HTTP        *http.Client
-	Buckets     *sync.Map
+	Token   string
+	HTTP    *http.Client
+	Buckets *sync.Map
+
+	// Hashes maps the method and shared route of requests to the bucket hash Discord assigned to
+	// them. Buckets of routes with a known hash are keyed by the hash and major parameter, so that
+	// every route with the hash draws from the same limit. Nil keys buckets by route only.
+	Hashes *sync.Map
+
 	GlobalReset time.Time
-	APIVersion  string
-	URLHost     string
-	URLScheme   string
+
+	// Global spaces out requests to stay under the global ratelimit, and InvalidRequests keeps
+	// invalid requests under the threshold for a ban. Either can be nil to disable it.
+	Global          *GlobalLimiter
+	InvalidRequests *InvalidRequestGuard
+
+	// Retry is the policy for retrying requests that fail with a 5xx status or a network error. Nil
+	// disables retries.
+	Retry *RetryPolicy
+
+	APIVersion string
+	URLHost    string
+	URLScheme  string
 }
 
 // NewClient makes a new client
 func NewClient(token string, apiVersion string) *Client {
 	return &Client{
-		Token:       token,
-		HTTP:        http.DefaultClient,
-		Buckets:     &sync.Map{},
-		GlobalReset: time.Time{},
-		APIVersion:  apiVersion,
-		URLHost:     "discord.com",
-		URLScheme:   "https",
+		Token:           token,
+		HTTP:            http.DefaultClient,
+		Buckets:         &sync.Map{},
+		Hashes:          &sync.Map{},
+		GlobalReset:     time.Time{},
+		Global:          NewGlobalLimiter(50),
+		InvalidRequests: NewInvalidRequestGuard(),
+		Retry:           DefaultRetryPolicy(),
+		APIVersion:      apiVersion,
+		URLHost:         "discord.com",
+		URLScheme:       "https",
 	}
 }
 
@@ -37,38 +60,107 @@ func (c *Client) GloballyLimited() bool {
 	return time.Now().Before(c.GlobalReset)
 }
 
-// DoJSON is a utility method for making JSON requests to the Discord API
-func (c *Client) DoJSON(method, url string, body io.Reader, respBody interface{}) error {
-	req, err := http.NewRequest(method, url, body)
+// DoJSON is a utility method for making JSON requests to the Discord API. Responses with a non-2xx
+// status return an *APIError, and the response body is only decoded if respBody is not nil.
+func (c *Client) DoJSON(method, url string, body io.Reader, respBody interface{}, opts ...RequestOption) error {
+	return c.DoJSONContext(context.Background(), method, url, body, respBody, opts...)
+}
+
+// DoJSONContext is like DoJSON, but gives up once the context is done, including while waiting for
+// ratelimits
+func (c *Client) DoJSONContext(ctx context.Context, method, url string, body io.Reader, respBody interface{}, opts ...RequestOption) error {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
 	if err != nil {
 		return err
 	}
 
-	res, err := c.Do(req)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return c.doJSON(req, respBody, opts)
+}
+
+// DoMultipart is like DoJSON, but sends a multipart form with files
+func (c *Client) DoMultipart(method, url string, form Form, respBody interface{}, opts ...RequestOption) error {
+	return c.DoMultipartContext(context.Background(), method, url, form, respBody, opts...)
+}
+
+// DoMultipartContext is like DoMultipart, but gives up once the context is done, including while
+// waiting for ratelimits
+func (c *Client) DoMultipartContext(ctx context.Context, method, url string, form Form, respBody interface{}, opts ...RequestOption) error {
+	req, err := NewMultipartRequest(ctx, method, url, form)
 	if err != nil {
 		return err
 	}
+	return c.doJSON(req, respBody, opts)
+}
 
-	err = json.NewDecoder(res.Body).Decode(respBody)
+// request makes a JSON request with body encoded as JSON, unless it is nil
+func (c *Client) request(ctx context.Context, method, url string, body, respBody interface{}, opts ...RequestOption) error {
+	var r io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(data)
+	}
+
+	return c.DoJSONContext(ctx, method, url, r, respBody, opts...)
+}
+
+// withQuery adds a query to a path, unless it is empty
+func withQuery(path string, query url.Values) string {
+	if len(query) == 0 {
+		return path
+	}
+	return path + "?" + query.Encode()
+}
+
+// requestFiles is like request, but uploads the files along with the body in a multipart form if
+// there are any
+func (c *Client) requestFiles(ctx context.Context, method, url string, body interface{}, files []File, respBody interface{}, opts ...RequestOption) error {
+	if len(files) == 0 {
+		return c.request(ctx, method, url, body, respBody, opts...)
+	}
+	return c.DoMultipartContext(ctx, method, url, Form{Payload: body, Files: files}, respBody, opts...)
+}
+
+// doJSON applies the options to a request, makes it and decodes its JSON response into respBody,
+// unless it is nil
+func (c *Client) doJSON(req *http.Request, respBody interface{}, opts []RequestOption) error {
+	for _, opt := range opts {
+		opt(req)
+	}
+
+	res, err := c.Do(req)
 	if err != nil {
 		return err
 	}
 
-	res.Body.Close()
-	return nil
+	if err = checkResponse(res); err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if respBody == nil || res.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	return json.NewDecoder(res.Body).Decode(respBody)
+}
+
+// DoContext is like Do, but gives up once the context is done, including while waiting for
+// ratelimits
+func (c *Client) DoContext(ctx context.Context, req *http.Request) (*http.Response, error) {
+	return c.Do(req.WithContext(ctx))
 }
 
-// Do makes a raw HTTP ratelimited request to the Discord API
+// Do makes a raw HTTP ratelimited request to the Discord API. The request is cancelled along with
+// its context.
 func (c *Client) Do(req *http.Request) (*http.Response, error) {
-	route := MakeRoute(req.URL.Path)
+	bucket := c.bucket(req.Method, req.URL.Path)
 	req.URL.Path = "/api/v" + c.APIVersion + req.URL.Path
 
-	bucket, _ := c.Buckets.Load(route)
-	if bucket == nil {
-		bucket = NewBucket(c, route)
-		c.Buckets.Store(route, bucket)
-	}
-
 	if req.URL.Host == "" {
 		req.URL.Host = c.URLHost
 	}
@@ -85,5 +177,54 @@ func (c *Client) Do(req *http.Request) (*http.Response, error) {
 		req.Header.Set("Authorization", "Bot "+c.Token)
 	}
 
-	return bucket.(*Bucket).Do(req)
+	return bucket.Do(req)
+}
+
+// wait waits for the client-wide limits to allow a request
+func (c *Client) wait(ctx context.Context) error {
+	if c.InvalidRequests != nil {
+		if err := c.InvalidRequests.Wait(ctx); err != nil {
+			return err
+		}
+	}
+
+	if c.Global != nil {
+		return c.Global.Wait(ctx)
+	}
+	return nil
+}
+
+// bucket returns the bucket of a request, which is keyed by its bucket hash if that is known and by
+// its route otherwise
+func (c *Client) bucket(method, path string) *Bucket {
+	shared, major := SplitMajor(MakeRoute(path))
+	route := method + " " + shared
+
+	key := route + ":" + major
+	if c.Hashes != nil {
+		if hash, ok := c.Hashes.Load(route); ok {
+			key = hash.(string) + ":" + major
+		}
+	}
+
+	if bucket, ok := c.Buckets.Load(key); ok {
+		return bucket.(*Bucket)
+	}
+
+	bucket := NewBucket(c, route)
+	bucket.Major = major
+	actual, _ := c.Buckets.LoadOrStore(key, bucket)
+	return actual.(*Bucket)
+}
+
+// learnHash records the hash Discord assigned to the route of a bucket. Later requests to the route
+// use the bucket of the hash, which is this one unless another route learned the hash first.
+func (c *Client) learnHash(b *Bucket, hash string) {
+	if hash == "" || hash == b.Hash || c.Hashes == nil {
+		return
+	}
+
+	b.Hash = hash
+	c.Hashes.Store(b.Route, hash)
+	c.Buckets.LoadOrStore(hash+":"+b.Major, b)
 }
@@ -1,10 +1,16 @@
 package rest_test
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/spec-tacles/go/rest"
@@ -50,3 +56,169 @@ func TestGateway(t *testing.T) {
 
 	t.Logf("%+v", info)
 }
+
+// newTestClient makes a client that sends requests to a fake Discord API
+func newTestClient(t *testing.T, handler http.HandlerFunc) *rest.Client {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := rest.NewClient("token", "8")
+	client.HTTP = server.Client()
+	client.URLHost = u.Host
+	client.URLScheme = u.Scheme
+	return client
+}
+
+func TestDoContext(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	req, err := http.NewRequest(http.MethodGet, "/users/@me", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	if _, err = client.DoContext(ctx, req); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		t.Errorf("request was not cancelled in time")
+	}
+}
+
+func TestDoJSONContextRatelimited(t *testing.T) {
+	requests := 0
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("X-RateLimit-Limit", "1")
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+		w.Write([]byte("{}"))
+	})
+
+	var user types.User
+	if err := client.DoJSON(http.MethodGet, "/users/@me", nil, &user); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if err := client.DoJSONContext(ctx, http.MethodGet, "/users/@me", nil, &user); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+	if requests != 1 {
+		t.Errorf("request was sent while ratelimited")
+	}
+}
+
+func TestBucketHash(t *testing.T) {
+	var requests []string
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		w.Header().Set("X-RateLimit-Bucket", "abcd")
+		w.Header().Set("X-RateLimit-Limit", "2")
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(2-len(requests)))
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+		w.Write([]byte("{}"))
+	})
+
+	do := func(ctx context.Context, path string) error {
+		var v interface{}
+		return client.DoJSONContext(ctx, http.MethodGet, path, nil, &v)
+	}
+
+	// both routes learn that they share a bucket
+	if err := do(context.Background(), "/channels/1/messages"); err != nil {
+		t.Fatal(err)
+	}
+	if err := do(context.Background(), "/channels/1/pins"); err != nil {
+		t.Fatal(err)
+	}
+
+	// the shared bucket is exhausted by one route, so the other has to wait
+	if err := do(context.Background(), "/channels/1/messages"); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := do(ctx, "/channels/1/pins"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+
+	// other major parameters have their own bucket
+	if err := do(context.Background(), "/channels/2/pins"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(requests) != 4 {
+		t.Errorf("unexpected requests %v", requests)
+	}
+}
+
+func TestBucketHashDisabled(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Bucket", "abcd")
+		w.Header().Set("X-RateLimit-Limit", "1")
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+		w.Write([]byte("{}"))
+	})
+	client.Hashes = nil
+
+	// without hashes, routes keep their own buckets
+	var v interface{}
+	if err := client.DoJSON(http.MethodGet, "/channels/1/messages", nil, &v); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.DoJSON(http.MethodGet, "/channels/1/pins", nil, &v); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAPIError(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{
+			"code": 50035,
+			"message": "Invalid Form Body",
+			"errors": {
+				"content": {"_errors": [{"code": "BASE_TYPE_MAX_LENGTH", "message": "Must be 2000 or fewer in length."}]},
+				"embeds": {"0": {"description": {"_errors": [{"code": "BASE_TYPE_REQUIRED", "message": "This field is required"}]}}}
+			}
+		}`))
+	})
+
+	var msg types.Message
+	err := client.DoJSON(http.MethodPost, "/channels/1/messages", nil, &msg)
+
+	var apiErr *rest.APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected an API error, got %v", err)
+	}
+
+	if apiErr.Status != http.StatusBadRequest || apiErr.Code != rest.CodeInvalidFormBody {
+		t.Errorf("unexpected status %d and code %d", apiErr.Status, apiErr.Code)
+	}
+	if apiErr.Errors["content"][0].Code != "BASE_TYPE_MAX_LENGTH" {
+		t.Errorf("unexpected content errors %+v", apiErr.Errors["content"])
+	}
+	if apiErr.Errors["embeds.0.description"][0].Code != "BASE_TYPE_REQUIRED" {
+		t.Errorf("unexpected embed errors %+v", apiErr.Errors)
+	}
+	t.Log(apiErr)
+}
@@ -0,0 +1,168 @@
+package rest
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrorCode is a JSON error code returned by the Discord API
+type ErrorCode int
+
+// Common JSON error codes. See https://discord.com/developers/docs/topics/opcodes-and-status-codes#json
+const (
+	CodeUnknownAccount     ErrorCode = 10001
+	CodeUnknownChannel     ErrorCode = 10003
+	CodeUnknownGuild       ErrorCode = 10004
+	CodeUnknownMember      ErrorCode = 10007
+	CodeUnknownMessage     ErrorCode = 10008
+	CodeUnknownRole        ErrorCode = 10011
+	CodeUnknownUser        ErrorCode = 10013
+	CodeUnknownEmoji       ErrorCode = 10014
+	CodeUnknownWebhook     ErrorCode = 10015
+	CodeUnknownBan         ErrorCode = 10026
+	CodeUnknownInteraction ErrorCode = 10062
+
+	CodeMaxPinsReached       ErrorCode = 30003
+	CodeMaxGuildRolesReached ErrorCode = 30005
+
+	CodeMessageAlreadyCrossposted ErrorCode = 40033
+
+	CodeMissingAccess            ErrorCode = 50001
+	CodeCannotExecuteOnDMChannel ErrorCode = 50003
+	CodeCannotEditOthersMessage  ErrorCode = 50005
+	CodeCannotSendEmptyMessage   ErrorCode = 50006
+	CodeCannotMessageUser        ErrorCode = 50007
+	CodeMissingPermissions       ErrorCode = 50013
+	CodeInvalidToken             ErrorCode = 50014
+	CodeBulkDeleteTooOld         ErrorCode = 50034
+	CodeInvalidFormBody          ErrorCode = 50035
+
+	CodeReactionBlocked ErrorCode = 90001
+
+	CodeAPIResourceOverloaded ErrorCode = 130000
+)
+
+// FieldError describes why a field of a request was invalid
+type FieldError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// APIError is returned for responses with a non-2xx status. Match specific errors by their Code:
+//
+//	var apiErr *rest.APIError
+//	if errors.As(err, &apiErr) && apiErr.Code == rest.CodeUnknownMessage {
+//		// the message was already deleted
+//	}
+type APIError struct {
+	Status  int       `json:"-"`
+	Code    ErrorCode `json:"code"`
+	Message string    `json:"message"`
+
+	// Errors holds the errors of each invalid field of the request, keyed by their path, such as
+	// "embeds.0.description"
+	Errors map[string][]FieldError `json:"-"`
+}
+
+func (e *APIError) Error() string {
+	var b strings.Builder
+	b.WriteString(strconv.Itoa(e.Status))
+	b.WriteString(" ")
+	b.WriteString(http.StatusText(e.Status))
+	if e.Message != "" {
+		b.WriteString(": ")
+		b.WriteString(e.Message)
+	}
+	if e.Code != 0 {
+		b.WriteString(" (")
+		b.WriteString(strconv.Itoa(int(e.Code)))
+		b.WriteString(")")
+	}
+
+	paths := make([]string, 0, len(e.Errors))
+	for path := range e.Errors {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		for _, fieldErr := range e.Errors[path] {
+			b.WriteString("; ")
+			b.WriteString(path)
+			b.WriteString(": ")
+			b.WriteString(fieldErr.Message)
+		}
+	}
+	return b.String()
+}
+
+// UnmarshalJSON decodes an error body, flattening its nested errors
+func (e *APIError) UnmarshalJSON(data []byte) error {
+	type apiError APIError
+	var body struct {
+		*apiError
+		Errors json.RawMessage `json:"errors"`
+	}
+	body.apiError = (*apiError)(e)
+
+	if err := json.Unmarshal(data, &body); err != nil {
+		return err
+	}
+
+	if len(body.Errors) == 0 {
+		return nil
+	}
+
+	e.Errors = make(map[string][]FieldError)
+	return flattenErrors(body.Errors, "", e.Errors)
+}
+
+// flattenErrors collects the "_errors" lists of a nested error object by their path
+func flattenErrors(data json.RawMessage, path string, into map[string][]FieldError) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	for key, value := range fields {
+		if key == "_errors" {
+			var errs []FieldError
+			if err := json.Unmarshal(value, &errs); err != nil {
+				return err
+			}
+			into[path] = append(into[path], errs...)
+			continue
+		}
+
+		child := key
+		if path != "" {
+			child = path + "." + key
+		}
+		if err := flattenErrors(value, child, into); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkResponse returns an *APIError for responses with a non-2xx status, consuming and closing
+// their body
+func checkResponse(res *http.Response) error {
+	if res.StatusCode >= 200 && res.StatusCode < 300 {
+		return nil
+	}
+	defer res.Body.Close()
+
+	apiErr := &APIError{Status: res.StatusCode}
+	body, err := ioutil.ReadAll(res.Body)
+	if err == nil && json.Unmarshal(body, apiErr) != nil {
+		apiErr.Message = strings.TrimSpace(string(body))
+	}
+
+	apiErr.Status = res.StatusCode
+	return apiErr
+}
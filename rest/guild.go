@@ -0,0 +1,308 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/bwmarrin/snowflake"
+	"github.com/spec-tacles/go/types"
+)
+
+// ModifyGuildParams are the settings of a guild to change. Nil fields are left as they are.
+type ModifyGuildParams struct {
+	Name                        string                            `json:"name,omitempty"`
+	Region                      *string                           `json:"region,omitempty"`
+	VerificationLevel           *types.VerificationLevel          `json:"verification_level,omitempty"`
+	DefaultMessageNotifications *types.MessageNotificationLevel   `json:"default_message_notifications,omitempty"`
+	ExplicitContentFilter       *types.ExplicitContentFilterLevel `json:"explicit_content_filter,omitempty"`
+	AFKChannelID                *snowflake.ID                     `json:"afk_channel_id,omitempty"`
+	AFKTimeout                  *int                              `json:"afk_timeout,omitempty"`
+	OwnerID                     snowflake.ID                      `json:"owner_id,omitempty"`
+	SystemChannelID             *snowflake.ID                     `json:"system_channel_id,omitempty"`
+
+	// Icon and Splash are data URIs of images
+	Icon   *string `json:"icon,omitempty"`
+	Splash *string `json:"splash,omitempty"`
+}
+
+// ListMembersParams select the members of a guild to list, in order of their user IDs
+type ListMembersParams struct {
+	// After is the highest user ID of the previous page
+	After snowflake.ID
+
+	// Limit is the number of members to list, from 1 to 1000. Discord defaults to 1.
+	Limit int
+}
+
+// AddMemberParams are the parameters of a user joining a guild
+type AddMemberParams struct {
+	// AccessToken is an OAuth2 access token of the user with the guilds.join scope
+	AccessToken string         `json:"access_token"`
+	Nick        string         `json:"nick,omitempty"`
+	Roles       []snowflake.ID `json:"roles,omitempty"`
+	Mute        bool           `json:"mute,omitempty"`
+	Deaf        bool           `json:"deaf,omitempty"`
+}
+
+// ModifyMemberParams are the fields of a member to change. Nil fields are left as they are.
+type ModifyMemberParams struct {
+	Nick  *string         `json:"nick,omitempty"`
+	Roles *[]snowflake.ID `json:"roles,omitempty"`
+	Mute  *bool           `json:"mute,omitempty"`
+	Deaf  *bool           `json:"deaf,omitempty"`
+
+	// ChannelID moves the member to another voice channel
+	ChannelID *snowflake.ID `json:"channel_id,omitempty"`
+}
+
+// PruneParams select the members to prune: those who have not been seen for Days days and have no
+// roles other than IncludeRoles
+type PruneParams struct {
+	// Days is from 1 to 30. Discord defaults to 7.
+	Days         int
+	IncludeRoles []snowflake.ID
+}
+
+func (p PruneParams) query() url.Values {
+	query := url.Values{}
+	if p.Days > 0 {
+		query.Set("days", strconv.Itoa(p.Days))
+	}
+	for _, id := range p.IncludeRoles {
+		query.Add("include_roles", id.String())
+	}
+	return query
+}
+
+// RoleParams are the fields of a role to create or change. Nil fields are left as they are, or
+// default for new roles.
+type RoleParams struct {
+	Name        *string `json:"name,omitempty"`
+	Permissions *int64  `json:"permissions,omitempty,string"`
+	Color       *int    `json:"color,omitempty"`
+	Hoist       *bool   `json:"hoist,omitempty"`
+	Mentionable *bool   `json:"mentionable,omitempty"`
+}
+
+// RolePosition is the position to move a role to
+type RolePosition struct {
+	ID       snowflake.ID `json:"id"`
+	Position int          `json:"position"`
+}
+
+func guildPath(guildID snowflake.ID) string {
+	return "/guilds/" + guildID.String()
+}
+
+func memberPath(guildID, userID snowflake.ID) string {
+	return guildPath(guildID) + "/members/" + userID.String()
+}
+
+// GetGuild gets a guild
+func (c *Client) GetGuild(ctx context.Context, guildID snowflake.ID, opts ...RequestOption) (*types.Guild, error) {
+	guild := &types.Guild{}
+	if err := c.request(ctx, http.MethodGet, guildPath(guildID), nil, guild, opts...); err != nil {
+		return nil, err
+	}
+	return guild, nil
+}
+
+// ModifyGuild changes the settings of a guild and returns the updated guild
+func (c *Client) ModifyGuild(ctx context.Context, guildID snowflake.ID, params ModifyGuildParams, opts ...RequestOption) (*types.Guild, error) {
+	guild := &types.Guild{}
+	if err := c.request(ctx, http.MethodPatch, guildPath(guildID), params, guild, opts...); err != nil {
+		return nil, err
+	}
+	return guild, nil
+}
+
+// GetMember gets a member of a guild
+func (c *Client) GetMember(ctx context.Context, guildID, userID snowflake.ID, opts ...RequestOption) (*types.GuildMember, error) {
+	member := &types.GuildMember{}
+	if err := c.request(ctx, http.MethodGet, memberPath(guildID, userID), nil, member, opts...); err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+// ListMembers lists members of a guild. Pass the ID of the last member as After to get the next
+// page.
+func (c *Client) ListMembers(ctx context.Context, guildID snowflake.ID, params ListMembersParams, opts ...RequestOption) ([]types.GuildMember, error) {
+	query := url.Values{}
+	if params.After != 0 {
+		query.Set("after", params.After.String())
+	}
+	if params.Limit > 0 {
+		query.Set("limit", strconv.Itoa(params.Limit))
+	}
+
+	var members []types.GuildMember
+	if err := c.request(ctx, http.MethodGet, withQuery(guildPath(guildID)+"/members", query), nil, &members, opts...); err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+// SearchMembers lists up to limit members of a guild whose username or nickname starts with query
+func (c *Client) SearchMembers(ctx context.Context, guildID snowflake.ID, query string, limit int, opts ...RequestOption) ([]types.GuildMember, error) {
+	values := url.Values{"query": {query}}
+	if limit > 0 {
+		values.Set("limit", strconv.Itoa(limit))
+	}
+
+	var members []types.GuildMember
+	if err := c.request(ctx, http.MethodGet, withQuery(guildPath(guildID)+"/members/search", values), nil, &members, opts...); err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+// AddMember adds a user to a guild and returns the new member, or nil if the user already was one
+func (c *Client) AddMember(ctx context.Context, guildID, userID snowflake.ID, params AddMemberParams, opts ...RequestOption) (*types.GuildMember, error) {
+	member := &types.GuildMember{}
+	if err := c.request(ctx, http.MethodPut, memberPath(guildID, userID), params, member, opts...); err != nil {
+		return nil, err
+	}
+
+	// Discord responds without content if the user is already a member
+	if member.User.ID == 0 {
+		return nil, nil
+	}
+	return member, nil
+}
+
+// ModifyMember changes a member of a guild and returns the updated member
+func (c *Client) ModifyMember(ctx context.Context, guildID, userID snowflake.ID, params ModifyMemberParams, opts ...RequestOption) (*types.GuildMember, error) {
+	member := &types.GuildMember{}
+	if err := c.request(ctx, http.MethodPatch, memberPath(guildID, userID), params, member, opts...); err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+// RemoveMember kicks a member from a guild
+func (c *Client) RemoveMember(ctx context.Context, guildID, userID snowflake.ID, opts ...RequestOption) error {
+	return c.request(ctx, http.MethodDelete, memberPath(guildID, userID), nil, nil, opts...)
+}
+
+// AddMemberRole gives a role to a member
+func (c *Client) AddMemberRole(ctx context.Context, guildID, userID, roleID snowflake.ID, opts ...RequestOption) error {
+	return c.request(ctx, http.MethodPut, memberPath(guildID, userID)+"/roles/"+roleID.String(), nil, nil, opts...)
+}
+
+// RemoveMemberRole takes a role from a member
+func (c *Client) RemoveMemberRole(ctx context.Context, guildID, userID, roleID snowflake.ID, opts ...RequestOption) error {
+	return c.request(ctx, http.MethodDelete, memberPath(guildID, userID)+"/roles/"+roleID.String(), nil, nil, opts...)
+}
+
+// GetBans gets the bans of a guild
+func (c *Client) GetBans(ctx context.Context, guildID snowflake.ID, opts ...RequestOption) ([]types.Ban, error) {
+	var bans []types.Ban
+	if err := c.request(ctx, http.MethodGet, guildPath(guildID)+"/bans", nil, &bans, opts...); err != nil {
+		return nil, err
+	}
+	return bans, nil
+}
+
+// GetBan gets the ban of a user from a guild
+func (c *Client) GetBan(ctx context.Context, guildID, userID snowflake.ID, opts ...RequestOption) (*types.Ban, error) {
+	ban := &types.Ban{}
+	if err := c.request(ctx, http.MethodGet, guildPath(guildID)+"/bans/"+userID.String(), nil, ban, opts...); err != nil {
+		return nil, err
+	}
+	return ban, nil
+}
+
+// CreateBan bans a user from a guild, deleting their messages of the last deleteMessageDays days,
+// from 0 to 7
+func (c *Client) CreateBan(ctx context.Context, guildID, userID snowflake.ID, deleteMessageDays int, opts ...RequestOption) error {
+	body := struct {
+		DeleteMessageDays int `json:"delete_message_days,omitempty"`
+	}{deleteMessageDays}
+	return c.request(ctx, http.MethodPut, guildPath(guildID)+"/bans/"+userID.String(), body, nil, opts...)
+}
+
+// RemoveBan unbans a user from a guild
+func (c *Client) RemoveBan(ctx context.Context, guildID, userID snowflake.ID, opts ...RequestOption) error {
+	return c.request(ctx, http.MethodDelete, guildPath(guildID)+"/bans/"+userID.String(), nil, nil, opts...)
+}
+
+type pruneCount struct {
+	Pruned *int `json:"pruned"`
+}
+
+// GetPruneCount returns the number of members that BeginPrune would kick
+func (c *Client) GetPruneCount(ctx context.Context, guildID snowflake.ID, params PruneParams, opts ...RequestOption) (int, error) {
+	res := pruneCount{}
+	if err := c.request(ctx, http.MethodGet, withQuery(guildPath(guildID)+"/prune", params.query()), nil, &res, opts...); err != nil {
+		return 0, err
+	}
+
+	if res.Pruned == nil {
+		return 0, nil
+	}
+	return *res.Pruned, nil
+}
+
+// BeginPrune kicks inactive members from a guild. It returns the number of kicked members if
+// computeCount is set, which Discord recommends against for large guilds.
+func (c *Client) BeginPrune(ctx context.Context, guildID snowflake.ID, params PruneParams, computeCount bool, opts ...RequestOption) (int, error) {
+	body := struct {
+		Days              int            `json:"days,omitempty"`
+		IncludeRoles      []snowflake.ID `json:"include_roles,omitempty"`
+		ComputePruneCount bool           `json:"compute_prune_count"`
+	}{params.Days, params.IncludeRoles, computeCount}
+
+	res := pruneCount{}
+	if err := c.request(ctx, http.MethodPost, guildPath(guildID)+"/prune", body, &res, opts...); err != nil {
+		return 0, err
+	}
+
+	if res.Pruned == nil {
+		return 0, nil
+	}
+	return *res.Pruned, nil
+}
+
+// GetRoles gets the roles of a guild
+func (c *Client) GetRoles(ctx context.Context, guildID snowflake.ID, opts ...RequestOption) ([]types.Role, error) {
+	var roles []types.Role
+	if err := c.request(ctx, http.MethodGet, guildPath(guildID)+"/roles", nil, &roles, opts...); err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
+// CreateRole creates a role in a guild
+func (c *Client) CreateRole(ctx context.Context, guildID snowflake.ID, params RoleParams, opts ...RequestOption) (*types.Role, error) {
+	role := &types.Role{}
+	if err := c.request(ctx, http.MethodPost, guildPath(guildID)+"/roles", params, role, opts...); err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+// ModifyRole changes a role of a guild and returns the updated role
+func (c *Client) ModifyRole(ctx context.Context, guildID, roleID snowflake.ID, params RoleParams, opts ...RequestOption) (*types.Role, error) {
+	role := &types.Role{}
+	if err := c.request(ctx, http.MethodPatch, guildPath(guildID)+"/roles/"+roleID.String(), params, role, opts...); err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+// ModifyRolePositions moves roles of a guild and returns all of its roles
+func (c *Client) ModifyRolePositions(ctx context.Context, guildID snowflake.ID, positions []RolePosition, opts ...RequestOption) ([]types.Role, error) {
+	var roles []types.Role
+	if err := c.request(ctx, http.MethodPatch, guildPath(guildID)+"/roles", positions, &roles, opts...); err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
+// DeleteRole deletes a role of a guild
+func (c *Client) DeleteRole(ctx context.Context, guildID, roleID snowflake.ID, opts ...RequestOption) error {
+	return c.request(ctx, http.MethodDelete, guildPath(guildID)+"/roles/"+roleID.String(), nil, nil, opts...)
+}
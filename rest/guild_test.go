@@ -0,0 +1,132 @@
+package rest_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/bwmarrin/snowflake"
+	"github.com/spec-tacles/go/rest"
+)
+
+func TestGuild(t *testing.T) {
+	ctx := context.Background()
+	client, requests := newRecordingClient(t, `{"id": "1", "name": "guild", "afk_channel_id": "2", "owner_id": "3", "application_id": "4", "system_channel_id": "5"}`)
+
+	guild, err := client.GetGuild(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if guild.ID != 1 || guild.Name != "guild" {
+		t.Errorf("unexpected guild %+v", guild)
+	}
+	assertRequest(t, *requests, request{http.MethodGet, "/guilds/1", ""})
+
+	timeout := 60
+	if _, err = client.ModifyGuild(ctx, 1, rest.ModifyGuildParams{AFKTimeout: &timeout}); err != nil {
+		t.Fatal(err)
+	}
+	assertRequest(t, *requests, request{http.MethodPatch, "/guilds/1", `{"afk_timeout":60}`})
+}
+
+func TestMembers(t *testing.T) {
+	ctx := context.Background()
+	client, requests := newRecordingClient(t, `[{"user": {"id": "2", "username": "user"}, "roles": ["3"]}]`)
+
+	members, err := client.ListMembers(ctx, 1, rest.ListMembersParams{After: 2, Limit: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(members) != 1 || members[0].User.ID != 2 || members[0].Roles[0] != 3 {
+		t.Errorf("unexpected members %+v", members)
+	}
+	assertRequest(t, *requests, request{http.MethodGet, "/guilds/1/members?after=2&limit=1000", ""})
+
+	if _, err = client.SearchMembers(ctx, 1, "us er", 10); err != nil {
+		t.Fatal(err)
+	}
+	assertRequest(t, *requests, request{http.MethodGet, "/guilds/1/members/search?limit=10&query=us+er", ""})
+
+	// adding a user that already is a member has no content
+	client, requests = newRecordingClient(t, "")
+	member, err := client.AddMember(ctx, 1, 2, rest.AddMemberParams{AccessToken: "token"})
+	if err != nil || member != nil {
+		t.Errorf("expected no member, got %+v and %v", member, err)
+	}
+	assertRequest(t, *requests, request{http.MethodPut, "/guilds/1/members/2", `{"access_token":"token"}`})
+
+	nick := ""
+	roles := []snowflake.ID{3}
+	if _, err = client.ModifyMember(ctx, 1, 2, rest.ModifyMemberParams{Nick: &nick, Roles: &roles}); err != nil {
+		t.Fatal(err)
+	}
+	assertRequest(t, *requests, request{http.MethodPatch, "/guilds/1/members/2", `{"nick":"","roles":["3"]}`})
+}
+
+func TestPrune(t *testing.T) {
+	ctx := context.Background()
+	client, requests := newRecordingClient(t, `{"pruned": 5}`)
+
+	params := rest.PruneParams{Days: 30, IncludeRoles: []snowflake.ID{3, 4}}
+	count, err := client.GetPruneCount(ctx, 1, params)
+	if err != nil || count != 5 {
+		t.Errorf("expected 5 members, got %d and %v", count, err)
+	}
+	assertRequest(t, *requests, request{http.MethodGet, "/guilds/1/prune?days=30&include_roles=3&include_roles=4", ""})
+
+	count, err = client.BeginPrune(ctx, 1, params, true)
+	if err != nil || count != 5 {
+		t.Errorf("expected 5 members, got %d and %v", count, err)
+	}
+	assertRequest(t, *requests, request{http.MethodPost, "/guilds/1/prune", `{"days":30,"include_roles":["3","4"],"compute_prune_count":true}`})
+}
+
+func TestRoles(t *testing.T) {
+	ctx := context.Background()
+	client, requests := newRecordingClient(t, `{"id": "3", "name": "mod", "permissions": 8}`)
+
+	name := "mod"
+	permissions := int64(8)
+	role, err := client.CreateRole(ctx, 1, rest.RoleParams{Name: &name, Permissions: &permissions})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if role.ID != 3 || role.Permissions != 8 {
+		t.Errorf("unexpected role %+v", role)
+	}
+	assertRequest(t, *requests, request{http.MethodPost, "/guilds/1/roles", `{"name":"mod","permissions":"8"}`})
+
+	client, requests = newRecordingClient(t, `[{"id": "3", "position": 2}, {"id": "4", "position": 1}]`)
+	roles, err := client.ModifyRolePositions(ctx, 1, []rest.RolePosition{{ID: 3, Position: 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(roles) != 2 || roles[0].Position != 2 {
+		t.Errorf("unexpected roles %+v", roles)
+	}
+	assertRequest(t, *requests, request{http.MethodPatch, "/guilds/1/roles", `[{"id":"3","position":2}]`})
+}
+
+func TestMemberActions(t *testing.T) {
+	ctx := context.Background()
+	client, requests := newRecordingClient(t, "")
+
+	tests := []struct {
+		do       func() error
+		expected request
+	}{
+		{func() error { return client.RemoveMember(ctx, 1, 2) }, request{http.MethodDelete, "/guilds/1/members/2", ""}},
+		{func() error { return client.AddMemberRole(ctx, 1, 2, 3) }, request{http.MethodPut, "/guilds/1/members/2/roles/3", ""}},
+		{func() error { return client.RemoveMemberRole(ctx, 1, 2, 3) }, request{http.MethodDelete, "/guilds/1/members/2/roles/3", ""}},
+		{func() error { return client.CreateBan(ctx, 1, 2, 7) }, request{http.MethodPut, "/guilds/1/bans/2", `{"delete_message_days":7}`}},
+		{func() error { return client.RemoveBan(ctx, 1, 2) }, request{http.MethodDelete, "/guilds/1/bans/2", ""}},
+		{func() error { return client.DeleteRole(ctx, 1, 3) }, request{http.MethodDelete, "/guilds/1/roles/3", ""}},
+	}
+
+	for _, test := range tests {
+		if err := test.do(); err != nil {
+			t.Fatal(err)
+		}
+		assertRequest(t, *requests, test.expected)
+	}
+}
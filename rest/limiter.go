@@ -0,0 +1,135 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrInvalidRequestLimit occurs when a request is refused because too many recent requests were
+// invalid
+var ErrInvalidRequestLimit = errors.New("too many invalid requests")
+
+// GlobalLimiter is a token bucket that keeps requests under Discord's global ratelimit
+// proactively, instead of waiting for a global 429
+type GlobalLimiter struct {
+	mux    sync.Mutex
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+// NewGlobalLimiter makes a limiter allowing the given number of requests per second, in bursts of
+// up to as many requests
+func NewGlobalLimiter(perSecond int) *GlobalLimiter {
+	return &GlobalLimiter{
+		rate:   float64(perSecond),
+		tokens: float64(perSecond),
+		last:   time.Now(),
+	}
+}
+
+// Wait takes a token, waiting until one is available or the context is done
+func (l *GlobalLimiter) Wait(ctx context.Context) error {
+	l.mux.Lock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.rate {
+		l.tokens = l.rate
+	}
+	l.last = now
+
+	// reserve the token even if it is not available yet, so that waiting requests are spaced out
+	l.tokens--
+	wait := time.Duration(-l.tokens / l.rate * float64(time.Second))
+	l.mux.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+
+	err := sleep(ctx, wait)
+	if err != nil {
+		l.mux.Lock()
+		l.tokens++
+		l.mux.Unlock()
+	}
+	return err
+}
+
+// InvalidRequestGuard counts responses with status 401, 403 and 429 within a sliding window.
+// Discord bans IPs that make 10,000 of these in 10 minutes, so the guard slows requests down as the
+// count gets close to Limit and refuses them once it is reached.
+type InvalidRequestGuard struct {
+	mux   sync.Mutex
+	times []time.Time
+
+	// Limit is the number of invalid requests within Window from which requests are refused
+	Limit  int
+	Window time.Duration
+
+	// SlowAfter is the number of invalid requests within Window from which requests are delayed to
+	// spread the rest of the limit over the window. Zero disables slowing down.
+	SlowAfter int
+}
+
+// NewInvalidRequestGuard makes a guard that leaves a margin below Discord's ban threshold
+func NewInvalidRequestGuard() *InvalidRequestGuard {
+	return &InvalidRequestGuard{
+		Limit:     9000,
+		Window:    10 * time.Minute,
+		SlowAfter: 5000,
+	}
+}
+
+// Count returns the number of invalid requests within the window
+func (g *InvalidRequestGuard) Count() int {
+	g.mux.Lock()
+	defer g.mux.Unlock()
+
+	g.prune(time.Now())
+	return len(g.times)
+}
+
+// Record counts a response if its status makes it invalid
+func (g *InvalidRequestGuard) Record(status int) {
+	switch status {
+	case 401, 403, 429:
+	default:
+		return
+	}
+
+	g.mux.Lock()
+	defer g.mux.Unlock()
+
+	now := time.Now()
+	g.prune(now)
+	g.times = append(g.times, now)
+}
+
+// Wait returns ErrInvalidRequestLimit if the limit is reached, and waits before returning if the
+// count is past SlowAfter
+func (g *InvalidRequestGuard) Wait(ctx context.Context) error {
+	count := g.Count()
+	if count >= g.Limit {
+		return ErrInvalidRequestLimit
+	}
+
+	if g.SlowAfter > 0 && count >= g.SlowAfter {
+		return sleep(ctx, g.Window/time.Duration(g.Limit-count))
+	}
+	return nil
+}
+
+// prune forgets invalid requests older than the window. Callers must hold the lock.
+func (g *InvalidRequestGuard) prune(now time.Time) {
+	i := 0
+	for i < len(g.times) && now.Sub(g.times[i]) >= g.Window {
+		i++
+	}
+
+	if i > 0 {
+		g.times = append(g.times[:0], g.times[i:]...)
+	}
+}
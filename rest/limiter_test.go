@@ -0,0 +1,82 @@
+package rest_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/spec-tacles/go/rest"
+)
+
+func TestGlobalLimiter(t *testing.T) {
+	ctx := context.Background()
+	l := rest.NewGlobalLimiter(20)
+
+	start := time.Now()
+	for i := 0; i < 20; i++ {
+		if err := l.Wait(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if time.Since(start) > 20*time.Millisecond {
+		t.Errorf("burst was limited")
+	}
+
+	if err := l.Wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(start) < 40*time.Millisecond {
+		t.Errorf("request after burst was not limited")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := l.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+}
+
+func TestInvalidRequestGuard(t *testing.T) {
+	requests := 0
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusForbidden)
+	})
+	guard := &rest.InvalidRequestGuard{Limit: 3, Window: time.Minute}
+	client.InvalidRequests = guard
+
+	do := func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, "/channels/1", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = client.Do(req)
+		return err
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := do(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := do(context.Background()); !errors.Is(err, rest.ErrInvalidRequestLimit) {
+		t.Errorf("expected invalid request limit, got %v", err)
+	}
+	if requests != 3 || guard.Count() != 3 {
+		t.Errorf("expected 3 invalid requests, got %d", requests)
+	}
+
+	// with 7 invalid requests left, requests are spaced a minute / 7 apart
+	guard.Limit = 10
+	guard.SlowAfter = 1
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := do(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected request to be slowed down, got %v", err)
+	}
+}
@@ -0,0 +1,175 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// File is a file uploaded with a multipart request
+type File struct {
+	// Field is the name of the form field of the file, which is files[n] by default for the nth file
+	Field       string
+	Name        string
+	ContentType string
+	Reader      io.Reader
+}
+
+// Form is the body of a multipart request
+type Form struct {
+	// Payload is encoded as JSON into the payload_json field, unless it is nil
+	Payload interface{}
+
+	// Fields are sent as plain form fields, for endpoints that do not take payload_json
+	Fields map[string]string
+
+	Files []File
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// NewMultipartRequest makes a request with the form as its body. The files are streamed as the
+// request is sent instead of being read into memory, so the request can only be rewound for retries
+// if every reader is an io.Seeker.
+func NewMultipartRequest(ctx context.Context, method, url string, form Form) (*http.Request, error) {
+	var (
+		payload []byte
+		err     error
+	)
+	if form.Payload != nil {
+		if payload, err = json.Marshal(form.Payload); err != nil {
+			return nil, err
+		}
+	}
+
+	body := &formBody{form: form, payload: payload, boundary: multipart.NewWriter(nil).Boundary()}
+	req, err := http.NewRequestWithContext(ctx, method, url, body.open())
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "multipart/form-data; boundary="+body.boundary)
+	if body.seekable() {
+		req.GetBody = body.rewind
+	}
+	return req, nil
+}
+
+// formBody writes a form into a pipe
+type formBody struct {
+	form     Form
+	payload  []byte
+	boundary string
+
+	mux     sync.Mutex
+	offsets []int64
+	last    *io.PipeReader
+	done    chan struct{}
+}
+
+// open starts writing the form into a new pipe and returns its reading end
+func (b *formBody) open() io.ReadCloser {
+	r, w := io.Pipe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		w.CloseWithError(b.write(w))
+	}()
+
+	b.last, b.done = r, done
+	return r
+}
+
+func (b *formBody) write(w io.Writer) error {
+	mw := multipart.NewWriter(w)
+	if err := mw.SetBoundary(b.boundary); err != nil {
+		return err
+	}
+
+	if b.payload != nil {
+		h := make(textproto.MIMEHeader)
+		h.Set("Content-Disposition", `form-data; name="payload_json"`)
+		h.Set("Content-Type", "application/json")
+
+		part, err := mw.CreatePart(h)
+		if err != nil {
+			return err
+		}
+		if _, err = part.Write(b.payload); err != nil {
+			return err
+		}
+	}
+
+	for name, value := range b.form.Fields {
+		if err := mw.WriteField(name, value); err != nil {
+			return err
+		}
+	}
+
+	for i, file := range b.form.Files {
+		field := file.Field
+		if field == "" {
+			field = "files[" + strconv.Itoa(i) + "]"
+		}
+
+		contentType := file.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+
+		h := make(textproto.MIMEHeader)
+		h.Set("Content-Disposition", `form-data; name="`+quoteEscaper.Replace(field)+`"; filename="`+quoteEscaper.Replace(file.Name)+`"`)
+		h.Set("Content-Type", contentType)
+
+		part, err := mw.CreatePart(h)
+		if err != nil {
+			return err
+		}
+		if _, err = io.Copy(part, file.Reader); err != nil {
+			return err
+		}
+	}
+
+	return mw.Close()
+}
+
+// seekable records the offsets of the files if they can all be rewound
+func (b *formBody) seekable() bool {
+	b.offsets = make([]int64, len(b.form.Files))
+	for i, file := range b.form.Files {
+		s, ok := file.Reader.(io.Seeker)
+		if !ok {
+			return false
+		}
+
+		offset, err := s.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return false
+		}
+		b.offsets[i] = offset
+	}
+	return true
+}
+
+// rewind stops writing the previous body, seeks the files back to where they started and opens a
+// new body
+func (b *formBody) rewind() (io.ReadCloser, error) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	b.last.Close()
+	<-b.done
+
+	for i, file := range b.form.Files {
+		if _, err := file.Reader.(io.Seeker).Seek(b.offsets[i], io.SeekStart); err != nil {
+			return nil, err
+		}
+	}
+	return b.open(), nil
+}
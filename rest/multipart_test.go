@@ -0,0 +1,126 @@
+package rest_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spec-tacles/go/rest"
+	"github.com/spec-tacles/go/types"
+)
+
+// readForm reads the parts of a multipart request by their field name
+func readForm(t *testing.T, r *http.Request) map[string]string {
+	mr, err := r.MultipartReader()
+	if err != nil {
+		t.Error(err)
+		return nil
+	}
+
+	parts := make(map[string]string)
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return parts
+		}
+		if err != nil {
+			t.Error(err)
+			return parts
+		}
+
+		data, _ := ioutil.ReadAll(part)
+		parts[part.FormName()] = part.FileName() + ":" + string(data)
+	}
+}
+
+func TestExecuteWebhook(t *testing.T) {
+	var parts map[string]string
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v8/webhooks/1/token" || r.URL.Query().Get("wait") != "true" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+
+		parts = readForm(t, r)
+		w.Write([]byte(`{"id": "2", "content": "hello"}`))
+	})
+
+	msg, err := client.ExecuteWebhook(context.Background(), 1, "token", rest.ExecuteWebhookParams{
+		Content: "hello",
+		Files: []rest.File{
+			{Name: "a.txt", Reader: strings.NewReader("first")},
+			{Name: `b "quoted".txt`, Reader: ioutil.NopCloser(strings.NewReader("second"))},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.ID != 2 {
+		t.Errorf("unexpected message %+v", msg)
+	}
+
+	var payload map[string]interface{}
+	if err = json.Unmarshal([]byte(strings.TrimPrefix(parts["payload_json"], ":")), &payload); err != nil || payload["content"] != "hello" {
+		t.Errorf("unexpected payload %q", parts["payload_json"])
+	}
+	if parts["files[0]"] != "a.txt:first" || parts["files[1]"] != `b "quoted".txt:second` {
+		t.Errorf("unexpected files %q", parts)
+	}
+}
+
+func TestCreateGuildSticker(t *testing.T) {
+	var parts map[string]string
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		parts = readForm(t, r)
+		w.Write([]byte(`{"id": "3", "name": "wave"}`))
+	})
+
+	sticker, err := client.CreateGuildSticker(context.Background(), 1, rest.CreateGuildStickerParams{
+		Name: "wave",
+		Tags: "wave",
+		File: rest.File{Name: "wave.png", ContentType: "image/png", Reader: bytes.NewReader([]byte("png"))},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sticker.ID != 3 || sticker.Name != "wave" {
+		t.Errorf("unexpected sticker %+v", sticker)
+	}
+	if parts["name"] != ":wave" || parts["file"] != "wave.png:png" {
+		t.Errorf("unexpected form %q", parts)
+	}
+}
+
+func TestMultipartRetry(t *testing.T) {
+	var bodies []string
+	client := newTestClient(t, failing(1, &bodies))
+	client.Retry.MinBackoff = time.Millisecond
+	client.Retry.RetryUnsafe = true
+
+	// files that can be rewound are sent again
+	file := strings.NewReader("contents")
+	err := client.DoMultipart(http.MethodPost, "/channels/1/messages", rest.Form{
+		Payload: types.Message{Content: "hello"},
+		Files:   []rest.File{{Name: "a.txt", Reader: file}},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bodies) != 2 || bodies[0] != bodies[1] || !strings.Contains(bodies[1], "contents") {
+		t.Errorf("unexpected attempts %q", bodies)
+	}
+
+	// streams are not buffered, so they are not retried
+	bodies = nil
+	err = client.DoMultipart(http.MethodPost, "/channels/1/messages", rest.Form{
+		Files: []rest.File{{Name: "a.txt", Reader: io.MultiReader(strings.NewReader("contents"))}},
+	}, nil)
+	if err == nil || len(bodies) != 1 {
+		t.Errorf("expected a single failed attempt, got %d and %v", len(bodies), err)
+	}
+}
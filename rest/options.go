@@ -0,0 +1,21 @@
+package rest
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// RequestOption changes a request before it is sent
+type RequestOption func(req *http.Request)
+
+// WithReason sets the reason shown in the audit log for the action of a request
+func WithReason(reason string) RequestOption {
+	return WithHeader("X-Audit-Log-Reason", url.PathEscape(reason))
+}
+
+// WithHeader sets a header of a request
+func WithHeader(key, value string) RequestOption {
+	return func(req *http.Request) {
+		req.Header.Set(key, value)
+	}
+}
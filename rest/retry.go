@@ -0,0 +1,117 @@
+package rest
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// RetryPolicy decides how requests that fail with a 5xx status or a network error are retried
+type RetryPolicy struct {
+	// MaxAttempts is the number of times a request is sent at most, including the first time
+	MaxAttempts int
+
+	// MinBackoff is the wait before the first retry, which doubles with every retry up to
+	// MaxBackoff. Each wait is randomly shortened by up to half to spread retries out.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	// RetryUnsafe retries requests with methods that are not idempotent, such as POST and PATCH,
+	// which Discord may have applied despite the failure
+	RetryUnsafe bool
+}
+
+// DefaultRetryPolicy returns the retry policy of new clients
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts: 3,
+		MinBackoff:  500 * time.Millisecond,
+		MaxBackoff:  10 * time.Second,
+	}
+}
+
+// allows returns whether the request may be retried at all
+func (p *RetryPolicy) allows(req *http.Request) bool {
+	if p == nil || p.MaxAttempts <= 1 {
+		return false
+	}
+
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return p.RetryUnsafe
+	}
+}
+
+func (p *RetryPolicy) shouldRetry(req *http.Request, res *http.Response, err error, attempt int) bool {
+	if !p.allows(req) || attempt >= p.MaxAttempts {
+		return false
+	}
+
+	// streamed bodies can only be sent again if they can be rewound
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+
+	if err != nil {
+		return req.Context().Err() == nil
+	}
+	return res.StatusCode >= 500 && res.StatusCode < 600
+}
+
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	backoff := p.MinBackoff << uint(attempt-1)
+	if backoff > p.MaxBackoff || backoff <= 0 {
+		backoff = p.MaxBackoff
+	}
+
+	half := int64(backoff / 2)
+	if half <= 0 {
+		return backoff
+	}
+	return time.Duration(half + rand.Int63n(half))
+}
+
+// bufferBody reads the body of a request into memory, unless it can be rewound already or is
+// streamed through a pipe
+func bufferBody(req *http.Request) error {
+	if req.Body == nil || req.Body == http.NoBody || req.GetBody != nil {
+		return nil
+	}
+
+	if _, ok := req.Body.(*io.PipeReader); ok {
+		return nil
+	}
+
+	body, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return err
+	}
+
+	req.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(body)), nil
+	}
+	req.Body, _ = req.GetBody()
+	return nil
+}
+
+// rewind returns a copy of a request to send again, with a fresh body
+func rewind(req *http.Request) (*http.Request, error) {
+	next := req.Clone(req.Context())
+	if req.GetBody == nil {
+		return next, nil
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+
+	next.Body = body
+	return next, nil
+}
@@ -0,0 +1,104 @@
+package rest_test
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spec-tacles/go/rest"
+)
+
+// failing responds with a 500 to the first n requests and records the bodies of all requests
+func failing(n int, bodies *[]string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		*bodies = append(*bodies, string(body))
+
+		if len(*bodies) <= n {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte("{}"))
+	}
+}
+
+func fastRetries(client *rest.Client) {
+	client.Retry.MinBackoff = time.Millisecond
+	client.Retry.MaxBackoff = 5 * time.Millisecond
+}
+
+func TestRetry(t *testing.T) {
+	var bodies []string
+	client := newTestClient(t, failing(2, &bodies))
+	fastRetries(client)
+
+	// a plain reader cannot be rewound, so the body has to be buffered
+	body := io.MultiReader(strings.NewReader(`{"name":`), strings.NewReader(`"test"}`))
+	if err := client.DoJSON(http.MethodPut, "/guilds/1/emojis/2", body, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(bodies) != 3 {
+		t.Fatalf("expected 3 attempts, got %d", len(bodies))
+	}
+	for _, b := range bodies {
+		if b != `{"name":"test"}` {
+			t.Errorf("unexpected body %q", b)
+		}
+	}
+}
+
+func TestRetryMaxAttempts(t *testing.T) {
+	var bodies []string
+	client := newTestClient(t, failing(10, &bodies))
+	fastRetries(client)
+
+	err := client.DoJSON(http.MethodGet, "/users/@me", nil, nil)
+
+	var apiErr *rest.APIError
+	if !errors.As(err, &apiErr) || apiErr.Status != http.StatusInternalServerError {
+		t.Errorf("expected an internal server error, got %v", err)
+	}
+	if len(bodies) != client.Retry.MaxAttempts {
+		t.Errorf("expected %d attempts, got %d", client.Retry.MaxAttempts, len(bodies))
+	}
+}
+
+func TestRetryUnsafe(t *testing.T) {
+	var bodies []string
+	client := newTestClient(t, failing(1, &bodies))
+	fastRetries(client)
+
+	if err := client.DoJSON(http.MethodPost, "/channels/1/messages", strings.NewReader("{}"), nil); err == nil {
+		t.Error("expected POST not to be retried")
+	}
+	if len(bodies) != 1 {
+		t.Errorf("expected 1 attempt, got %d", len(bodies))
+	}
+
+	bodies = nil
+	client.Retry.RetryUnsafe = true
+	if err := client.DoJSON(http.MethodPost, "/channels/1/messages", strings.NewReader("{}"), nil); err != nil {
+		t.Error(err)
+	}
+	if len(bodies) != 2 || bodies[1] != "{}" {
+		t.Errorf("unexpected attempts %q", bodies)
+	}
+}
+
+func TestRetryDisabled(t *testing.T) {
+	var bodies []string
+	client := newTestClient(t, failing(1, &bodies))
+	client.Retry = nil
+
+	if err := client.DoJSON(http.MethodGet, "/users/@me", nil, nil); err == nil {
+		t.Error("expected the request not to be retried")
+	}
+	if len(bodies) != 1 {
+		t.Errorf("expected 1 attempt, got %d", len(bodies))
+	}
+}
@@ -13,6 +13,9 @@ const (
 
 	// IDNotation is a string representing an ID that has been replaced in the formation of a ratelimit route
 	IDNotation = PathSep + ":id"
+
+	// MajorNotation is a string representing a major parameter that has been replaced in a shared route
+	MajorNotation = PathSep + ":major"
 )
 
 // MakeRoute makes a ratelimit route given a path
@@ -49,3 +52,24 @@ func buildRoute(params []string, route string, i int) string {
 
 	return route
 }
+
+// SplitMajor splits a ratelimit route made by MakeRoute into the route shared by every value of its
+// major parameter and the value of the major parameter. Routes without a major parameter are
+// returned as they are.
+func SplitMajor(route string) (shared, major string) {
+	var params = strings.SplitN(route, PathSep, 4)
+	if len(params) < 3 || params[0] != "" {
+		return route, ""
+	}
+
+	switch params[1] {
+	case "channels", "guilds", "webhooks":
+		shared = PathSep + params[1] + MajorNotation
+		if len(params) == 4 {
+			shared += PathSep + params[3]
+		}
+		return shared, params[2]
+	}
+
+	return route, ""
+}
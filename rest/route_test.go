@@ -22,3 +22,19 @@ func TestMakeRoute(t *testing.T) {
 		}
 	}
 }
+
+var majors = map[string][2]string{
+	"/guilds/1/members/:id/roles": {"/guilds/:major/members/:id/roles", "1"},
+	"/channels/2":                 {"/channels/:major", "2"},
+	"/channels/2/messages/:id":    {"/channels/:major/messages/:id", "2"},
+	"/users/:id":                  {"/users/:id", ""},
+	"channels":                    {"channels", ""},
+}
+
+func TestSplitMajor(t *testing.T) {
+	for route, expected := range majors {
+		if shared, major := rest.SplitMajor(route); shared != expected[0] || major != expected[1] {
+			t.Errorf("Split route '%s' into ('%s', '%s'), expected ('%s', '%s')", route, shared, major, expected[0], expected[1])
+		}
+	}
+}
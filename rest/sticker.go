@@ -0,0 +1,41 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/bwmarrin/snowflake"
+	"github.com/spec-tacles/go/types"
+)
+
+// CreateGuildStickerParams are the parameters of a new guild sticker
+type CreateGuildStickerParams struct {
+	Name        string
+	Description string
+
+	// Tags is the name of an emoji related to the sticker
+	Tags string
+
+	// File is a PNG, APNG or Lottie JSON file of the sticker
+	File File
+}
+
+// CreateGuildSticker uploads a sticker to a guild
+func (c *Client) CreateGuildSticker(ctx context.Context, guildID snowflake.ID, params CreateGuildStickerParams, opts ...RequestOption) (*types.Sticker, error) {
+	file := params.File
+	file.Field = "file"
+
+	sticker := &types.Sticker{}
+	err := c.DoMultipartContext(ctx, http.MethodPost, "/guilds/"+guildID.String()+"/stickers", Form{
+		Fields: map[string]string{
+			"name":        params.Name,
+			"description": params.Description,
+			"tags":        params.Tags,
+		},
+		Files: []File{file},
+	}, sticker, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return sticker, nil
+}
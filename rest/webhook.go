@@ -0,0 +1,31 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/bwmarrin/snowflake"
+	"github.com/spec-tacles/go/types"
+)
+
+// ExecuteWebhookParams are the parameters of a message sent by a webhook
+type ExecuteWebhookParams struct {
+	Content   string        `json:"content,omitempty"`
+	Username  string        `json:"username,omitempty"`
+	AvatarURL string        `json:"avatar_url,omitempty"`
+	TTS       bool          `json:"tts,omitempty"`
+	Embeds    []types.Embed `json:"embeds,omitempty"`
+
+	// Files are uploaded as attachments of the message
+	Files []File `json:"-"`
+}
+
+// ExecuteWebhook sends a message with a webhook and returns the message
+func (c *Client) ExecuteWebhook(ctx context.Context, webhookID snowflake.ID, token string, params ExecuteWebhookParams, opts ...RequestOption) (*types.Message, error) {
+	msg := &types.Message{}
+	err := c.requestFiles(ctx, http.MethodPost, "/webhooks/"+webhookID.String()+"/"+token+"?wait=true", params, params.Files, msg, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
@@ -92,6 +92,12 @@ type GuildUpdate Guild
 // GuildDelete represents a guild delete packet
 type GuildDelete UnavailableGuild
 
+// Ban represents a ban of a user from a guild
+type Ban struct {
+	Reason string `json:"reason"`
+	User   User   `json:"user"`
+}
+
 // GuildBanAdd represents a guild ban add packet
 type GuildBanAdd struct {
 	GuildID snowflake.ID `json:"guild_id"`
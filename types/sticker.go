@@ -0,0 +1,28 @@
+package types
+
+import "github.com/bwmarrin/snowflake"
+
+// StickerFormatType represents the file format of a sticker
+type StickerFormatType int
+
+// Sticker format types
+const (
+	StickerFormatTypePNG StickerFormatType = iota + 1
+	StickerFormatTypeAPNG
+	StickerFormatTypeLottie
+)
+
+// Sticker represents a sticker on Discord
+type Sticker struct {
+	ID          snowflake.ID      `json:"id"`
+	PackID      snowflake.ID      `json:"pack_id,omitempty"`
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Tags        string            `json:"tags"`
+	Type        int               `json:"type"`
+	FormatType  StickerFormatType `json:"format_type"`
+	Available   bool              `json:"available,omitempty"`
+	GuildID     snowflake.ID      `json:"guild_id,omitempty"`
+	User        *User             `json:"user,omitempty"`
+	SortValue   int               `json:"sort_value,omitempty"`
+}